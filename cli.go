@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/codaea/simpleprint/server"
+)
+
+// commands are the subcommands that work without the server, main serves when there is none
+var commands = map[string]func(args []string, stdin io.Reader, stdout io.Writer) error{
+	"print":         runPrint,
+	"preview":       runPreview,
+	"test-page":     runTestPage,
+	"self-test":     runSelfTest,
+	"list-printers": runListPrinters,
+	"status":        runStatus,
+}
+
+const usage = `Usage: simpleprint [command]
+
+Commands:
+  serve                          run the HTTP server, the default
+  print [-printer uri] <file>    print a request from a JSON, YAML or TOML file, - reads JSON from stdin
+  preview -o out.png <file>      draw a request as a PNG instead of printing it
+  test-page [-printer uri]       print a test page
+  self-test [-printer uri]       print the self-test page with every font, code page, barcode and dither mode,
+            [-o out.png]         or draw it as a PNG
+  list-printers                  list the USB printers
+  status [-printer uri]          show the printer status, exits with 1 if something is wrong
+`
+
+// errProblems is returned by status when the printer isn't ready, after the problems are printed
+var errProblems = errors.New("the printer isn't ready")
+
+// parseFlags parses flags before and after the positional arguments, so `print file.json -printer /dev/usb/lp1` works
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// printerFlag is the -printer flag, the printer of the profile by default
+func printerFlag(fs *flag.FlagSet) *string {
+	_, profile := server.ActiveProfile()
+	return fs.String("printer", profile.URI, "URI or path of the printer, the first USB printer if empty")
+}
+
+func openPrinter(uri string) (*render.ESCPOS, error) {
+	p, err := server.OpenPrinter(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to printer: %w", err)
+	}
+	return p, nil
+}
+
+// readRequest decodes the request in the file at name, or JSON from stdin if name is -
+func readRequest(name string, stdin io.Reader) (receipt.Request, error) {
+	var body []byte
+	var err error
+	if name == "-" {
+		body, err = io.ReadAll(stdin)
+	} else {
+		body, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return receipt.Request{}, err
+	}
+	return server.DecodeFile(name, body)
+}
+
+func runPrint(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("print", flag.ContinueOnError)
+	printer := printerFlag(fs)
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("print takes one file, or - for stdin")
+	}
+
+	req, err := readRequest(files[0], stdin)
+	if err != nil {
+		return err
+	}
+	p, err := openPrinter(*printer)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	return server.Print(p, req)
+}
+
+func runPreview(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("preview", flag.ContinueOnError)
+	out := fs.String("o", "", "PNG file to write, stdout if empty")
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("preview takes one file, or - for stdin")
+	}
+
+	req, err := readRequest(files[0], stdin)
+	if err != nil {
+		return err
+	}
+	return writePreview(req, *out, stdout)
+}
+
+// writePreview draws req as a PNG in the file out, or on stdout if out is empty
+func writePreview(req receipt.Request, out string, stdout io.Writer) error {
+	preview, err := render.NewPreview()
+	if err != nil {
+		return err
+	}
+	if err := render.Print(preview, req); err != nil {
+		return err
+	}
+
+	w := stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return png.Encode(w, preview.Paper())
+}
+
+func runTestPage(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("test-page", flag.ContinueOnError)
+	printer := printerFlag(fs)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	req, err := server.TestPage(*printer)
+	if err != nil {
+		return err
+	}
+	p, err := openPrinter(*printer)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	return server.Print(p, req)
+}
+
+func runSelfTest(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("self-test", flag.ContinueOnError)
+	printer := printerFlag(fs)
+	out := fs.String("o", "", "PNG file to draw the page in instead of printing it")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	req, err := server.SelfTest(*printer)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		return writePreview(req, *out, stdout)
+	}
+	p, err := openPrinter(*printer)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	return server.Print(p, req)
+}
+
+func runListPrinters(args []string, stdin io.Reader, stdout io.Writer) error {
+	devices, err := render.ListDevices()
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		fmt.Fprintln(stdout, "No printers found")
+		return nil
+	}
+	for _, d := range devices {
+		name := strings.TrimSpace(d.Manufacturer + " " + d.Product)
+		if name == "" {
+			name = "unknown printer"
+		}
+		fmt.Fprintf(stdout, "%s\t%s", d.Path, name)
+		if d.VendorID != "" {
+			fmt.Fprintf(stdout, " (%s:%s)", d.VendorID, d.ProductID)
+		}
+		if d.Serial != "" {
+			fmt.Fprintf(stdout, " serial %s", d.Serial)
+		}
+		fmt.Fprintln(stdout)
+	}
+	return nil
+}
+
+func runStatus(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	printer := printerFlag(fs)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	p, err := openPrinter(*printer)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	status, err := p.Status()
+	if err != nil {
+		return err
+	}
+	problems := status.Problems()
+	if len(problems) == 0 {
+		fmt.Fprintln(stdout, "ready")
+		return nil
+	}
+	fmt.Fprintln(stdout, strings.Join(problems, ", "))
+	return errProblems
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFlags_Interspersed(t *testing.T) {
+	for _, args := range [][]string{
+		{"-printer", "/dev/usb/lp1", "receipt.json"},
+		{"receipt.json", "-printer", "/dev/usb/lp1"},
+	} {
+		fs := flag.NewFlagSet("print", flag.ContinueOnError)
+		printer := printerFlag(fs)
+		files, err := parseFlags(fs, args)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"receipt.json"}, files)
+		assert.Equal(t, "/dev/usb/lp1", *printer)
+	}
+}
+
+func TestRunPreview(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "receipt.yaml")
+	os.WriteFile(file, []byte("receipt:\n  - type: line\n    content: Hello\n  - type: qr\n    code: https://example.com\n"), 0o644)
+	out := filepath.Join(dir, "out.png")
+
+	assert.NoError(t, runPreview([]string{file, "-o", out}, nil, nil))
+	f, err := os.Open(out)
+	assert.NoError(t, err)
+	defer f.Close()
+	img, err := png.Decode(f)
+	assert.NoError(t, err)
+	assert.Equal(t, 576, img.Bounds().Dx())
+	assert.Greater(t, img.Bounds().Dy(), 100)
+}
+
+func TestRunPreview_Stdin(t *testing.T) {
+	var out bytes.Buffer
+	stdin := strings.NewReader(`{"receipt": [{"type": "line", "content": "Hello"}]}`)
+	assert.NoError(t, runPreview([]string{"-"}, stdin, &out))
+	_, err := png.Decode(&out)
+	assert.NoError(t, err)
+
+	assert.Error(t, runPreview([]string{"-"}, strings.NewReader(`{"receipt": [{"type": "sparkles"}]}`), &out))
+	assert.Error(t, runPreview(nil, nil, &out))
+}
+
+func TestRunPrint(t *testing.T) {
+	dir := t.TempDir()
+	printer := filepath.Join(dir, "lp0")
+	os.WriteFile(printer, nil, 0o644)
+	stdin := strings.NewReader(`{"receipt": [{"type": "line", "content": "Hello"}]}`)
+
+	assert.NoError(t, runPrint([]string{"-printer", printer, "-"}, stdin, nil))
+	printed, _ := os.ReadFile(printer)
+	assert.Contains(t, string(printed), "Hello")
+}
+
+func TestRunTestPage(t *testing.T) {
+	printer := filepath.Join(t.TempDir(), "lp0")
+	os.WriteFile(printer, nil, 0o644)
+
+	assert.NoError(t, runTestPage([]string{"-printer", printer}, nil, nil))
+	printed, _ := os.ReadFile(printer)
+	assert.Contains(t, string(printed), "simpleprint test page")
+	assert.Contains(t, string(printed), "123456789012345678901234567890123456789012345678")
+}
+
+func TestRunSelfTest_Preview(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "self-test.png")
+	assert.NoError(t, runSelfTest([]string{"-o", out}, nil, nil))
+	f, err := os.Open(out)
+	assert.NoError(t, err)
+	defer f.Close()
+	_, err = png.Decode(f)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,202 @@
+// Package client talks to a SimplePrint server
+//
+//	c := client.New("http://localhost:5010")
+//	err := c.Print(ctx, receipt.New().Line("Hello", receipt.Bold).Build())
+//	var busy *client.BusyError
+//	if errors.As(err, &busy) {
+//		// another job is printing, try again later
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/codaea/simpleprint/receipt"
+)
+
+// BusyError means another job was printing, nothing was printed and the request can be sent again
+type BusyError struct {
+	Message string
+}
+
+func (e *BusyError) Error() string {
+	return "printer is busy: " + e.Message
+}
+
+// InvalidError means the server rejected the request, Errors has every invalid field if it was a print request
+type InvalidError struct {
+	StatusCode int
+	Message    string
+	Errors     []receipt.FieldError
+}
+
+func (e *InvalidError) Error() string {
+	return fmt.Sprintf("invalid request (%d): %s", e.StatusCode, e.Message)
+}
+
+// FailedError means the server or the printer failed to do what was asked
+type FailedError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *FailedError) Error() string {
+	return fmt.Sprintf("request failed (%d): %s", e.StatusCode, e.Message)
+}
+
+type Client struct {
+	// BaseURL is where the server is, like http://localhost:5010
+	BaseURL string
+	// HTTPClient sends the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Print prints a receipt
+func (c *Client) Print(ctx context.Context, req receipt.Request) error {
+	return c.postJSON(ctx, "/print", req, nil)
+}
+
+// Validate checks a receipt without printing it, the result has the warnings
+// as well as the errors. An invalid receipt is not an error of Validate.
+func (c *Client) Validate(ctx context.Context, req receipt.Request) (receipt.ValidationResult, error) {
+	var result receipt.ValidationResult
+	err := c.postJSON(ctx, "/validate", req, &result)
+	return result, err
+}
+
+// PrintTemplate renders the template stored under name with data and prints it,
+// version 0 is the latest version
+func (c *Client) PrintTemplate(ctx context.Context, name string, version int, data any) error {
+	path := "/templates/" + url.PathEscape(name) + "/print"
+	if version > 0 {
+		path += "?version=" + strconv.Itoa(version)
+	}
+	return c.postJSON(ctx, path, data, nil)
+}
+
+// PrintMarkdown prints a markdown document
+func (c *Client) PrintMarkdown(ctx context.Context, markdown string) error {
+	return c.do(ctx, "/print/markdown", "text/markdown", strings.NewReader(markdown), nil)
+}
+
+// PrintHTML prints an HTML document, mode is text or raster, or empty for text
+func (c *Client) PrintHTML(ctx context.Context, html, mode string) error {
+	path := "/print/html"
+	if mode != "" {
+		path += "?mode=" + url.QueryEscape(mode)
+	}
+	return c.do(ctx, path, "text/html", strings.NewReader(html), nil)
+}
+
+// TextOptions format plain text, zero values use the server's defaults
+type TextOptions struct {
+	Font      receipt.FontType
+	Size      int
+	Alignment receipt.AlignmentType
+	// Markup prints **bold**, __underline__ and --- separators
+	Markup bool
+}
+
+// PrintText prints plain text
+func (c *Client) PrintText(ctx context.Context, text string, opts TextOptions) error {
+	query := url.Values{}
+	if opts.Font != "" {
+		query.Set("font", string(opts.Font))
+	}
+	if opts.Size != 0 {
+		query.Set("size", strconv.Itoa(opts.Size))
+	}
+	if opts.Alignment != "" {
+		query.Set("alignment", string(opts.Alignment))
+	}
+	if opts.Markup {
+		query.Set("markup", "true")
+	}
+	path := "/print/text"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return c.do(ctx, path, "text/plain", strings.NewReader(text), nil)
+}
+
+// OpenDrawer kicks the cash drawer without printing, d can be nil for the default pulse
+func (c *Client) OpenDrawer(ctx context.Context, d *receipt.Drawer) error {
+	if d == nil {
+		d = &receipt.Drawer{}
+	}
+	return c.postJSON(ctx, "/drawer/open", d, nil)
+}
+
+func (c *Client) postJSON(ctx context.Context, path string, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, path, "application/json", bytes.NewReader(data), result)
+}
+
+// do sends a POST request and decodes the JSON response into result, or turns it into one of the error types
+func (c *Client) do(ctx context.Context, path, contentType string, body io.Reader, result any) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusOK {
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(data, result)
+	}
+	return responseError(resp.StatusCode, data)
+}
+
+func responseError(status int, body []byte) error {
+	var resp struct {
+		Error   string               `json:"error"`
+		Message string               `json:"message"`
+		Errors  []receipt.FieldError `json:"errors"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Error == "" {
+		resp.Error = strings.TrimSpace(string(body))
+	}
+
+	switch {
+	case status == http.StatusServiceUnavailable:
+		msg := resp.Message
+		if msg == "" {
+			msg = resp.Error
+		}
+		return &BusyError{Message: msg}
+	case status >= 400 && status < 500:
+		return &InvalidError{StatusCode: status, Message: resp.Error, Errors: resp.Errors}
+	}
+	return &FailedError{StatusCode: status, Message: resp.Error}
+}
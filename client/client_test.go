@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/stretchr/testify/assert"
+)
+
+// testServer answers every request with status and body, and records the last request
+func testServer(t *testing.T, status int, body string) (*Client, *http.Request, *[]byte) {
+	var got http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = *r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return New(srv.URL + "/"), &got, &gotBody
+}
+
+func TestPrint(t *testing.T) {
+	c, got, body := testServer(t, 200, `{"success": true}`)
+
+	err := c.Print(context.Background(), receipt.New().Line("Hi", receipt.Bold).Build())
+	assert.NoError(t, err)
+	assert.Equal(t, "/print", got.URL.Path)
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"receipt": [{"type": "line", "content": "Hi", "bold": true}]}`, string(*body))
+}
+
+func TestPrint_Busy(t *testing.T) {
+	c, _, _ := testServer(t, 503, `{"error": "Printer is busy", "message": "Another print job is currently in progress. Please try again later."}`)
+
+	err := c.Print(context.Background(), receipt.New().Line("Hi").Build())
+	var busy *BusyError
+	assert.True(t, errors.As(err, &busy))
+	assert.Contains(t, busy.Message, "Another print job")
+}
+
+func TestPrint_Invalid(t *testing.T) {
+	c, _, _ := testServer(t, 400, `{
+		"error": "/receipt/0/font_size: invalid font_size: 12. Must be 1-8",
+		"errors": [{"path": "/receipt/0/font_size", "message": "invalid font_size: 12. Must be 1-8"}]
+	}`)
+
+	err := c.Print(context.Background(), receipt.New().Line("Hi", receipt.Size(12)).Build())
+	var invalid *InvalidError
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, 400, invalid.StatusCode)
+	assert.Equal(t, []receipt.FieldError{{Path: "/receipt/0/font_size", Message: "invalid font_size: 12. Must be 1-8"}}, invalid.Errors)
+}
+
+func TestPrint_Failed(t *testing.T) {
+	c, _, _ := testServer(t, 500, `{"error": "printer is not connected"}`)
+
+	err := c.Print(context.Background(), receipt.New().Line("Hi").Build())
+	var failed *FailedError
+	assert.True(t, errors.As(err, &failed))
+	assert.Equal(t, 500, failed.StatusCode)
+	assert.Equal(t, "printer is not connected", failed.Message)
+
+	c, _, _ = testServer(t, 502, "Bad Gateway")
+	err = c.Print(context.Background(), receipt.New().Line("Hi").Build())
+	assert.True(t, errors.As(err, &failed))
+	assert.Equal(t, "Bad Gateway", failed.Message)
+}
+
+func TestValidate(t *testing.T) {
+	c, got, _ := testServer(t, 200, `{"valid": true, "errors": [], "warnings": [{"path": "/receipt/0/content", "message": "content is 60 characters, only 48 fit on a line so it wraps"}]}`)
+
+	result, err := c.Validate(context.Background(), receipt.New().Line("Hi").Build())
+	assert.NoError(t, err)
+	assert.Equal(t, "/validate", got.URL.Path)
+	assert.True(t, result.Valid)
+	assert.Len(t, result.Warnings, 1)
+}
+
+func TestPrintText(t *testing.T) {
+	c, got, body := testServer(t, 200, `{"success": true}`)
+
+	err := c.PrintText(context.Background(), "hello", TextOptions{Size: 2, Alignment: receipt.AlignCenter, Markup: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "/print/text", got.URL.Path)
+	assert.Equal(t, "2", got.URL.Query().Get("size"))
+	assert.Equal(t, "center", got.URL.Query().Get("alignment"))
+	assert.Equal(t, "true", got.URL.Query().Get("markup"))
+	assert.Equal(t, "", got.URL.Query().Get("font"))
+	assert.Equal(t, "text/plain", got.Header.Get("Content-Type"))
+	assert.Equal(t, "hello", string(*body))
+}
+
+func TestPrintTemplate(t *testing.T) {
+	c, got, body := testServer(t, 200, `{"success": true}`)
+
+	err := c.PrintTemplate(context.Background(), "order", 3, map[string]any{"id": 7})
+	assert.NoError(t, err)
+	assert.Equal(t, "/templates/order/print", got.URL.Path)
+	assert.Equal(t, "3", got.URL.Query().Get("version"))
+	assert.JSONEq(t, `{"id": 7}`, string(*body))
+}
+
+func TestOpenDrawer(t *testing.T) {
+	c, got, body := testServer(t, 200, `{"success": true}`)
+
+	assert.NoError(t, c.OpenDrawer(context.Background(), nil))
+	assert.Equal(t, "/drawer/open", got.URL.Path)
+	var d map[string]any
+	assert.NoError(t, json.Unmarshal(*body, &d))
+	assert.Equal(t, "drawer", d["type"])
+}
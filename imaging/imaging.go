@@ -0,0 +1,86 @@
+// Package imaging turns images into the black and white bitmaps a thermal printer burns
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"strings"
+
+	"github.com/makeworld-the-better-one/dither/v2"
+	"golang.org/x/image/draw"
+)
+
+// Decode decodes base64 image data, with or without a data URI prefix
+func Decode(data string) (image.Image, error) {
+	if strings.HasPrefix(data, "data:") {
+		if _, after, ok := strings.Cut(data, ","); ok {
+			data = after
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(decoded))
+	return img, err
+}
+
+// EncodeDataURI encodes img as a PNG data URI, the form Decode and image items take
+func EncodeDataURI(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Scale resizes img to the given width keeping the aspect ratio.
+// A width of 0 keeps the original size, and nothing is ever wider than maxWidth.
+func Scale(img image.Image, width, maxWidth int) image.Image {
+	b := img.Bounds()
+	if width <= 0 {
+		width = b.Dx()
+	}
+	if width > maxWidth {
+		width = maxWidth
+	}
+	if width == b.Dx() || b.Dx() == 0 {
+		return img
+	}
+
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// Dither turns img into black and white with Floyd-Steinberg error diffusion, so gray tones become dot patterns
+func Dither(img image.Image) image.Image {
+	d := dither.NewDitherer([]color.Color{color.Black, color.White})
+	d.Matrix = dither.FloydSteinberg
+	return d.Dither(img)
+}
+
+// BlackPixels thresholds img to the dots a printer would burn, transparent pixels are white
+func BlackPixels(img image.Image) (width, height int, pixels [][]bool) {
+	b := img.Bounds()
+	width, height = b.Dx(), b.Dy()
+	pixels = make([][]bool, height)
+	for y := 0; y < height; y++ {
+		pixels[y] = make([]bool, width)
+		for x := 0; x < width; x++ {
+			r, g, bl, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			luminance := (r*299 + g*587 + bl*114) / 1000
+			pixels[y][x] = a >= 0x8000 && luminance < 0x8000
+		}
+	}
+	return width, height, pixels
+}
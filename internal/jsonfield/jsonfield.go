@@ -0,0 +1,28 @@
+// Package jsonfield finds the fields of a struct the way encoding/json does,
+// for decoding field by field and for generating schemas
+package jsonfield
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Fields returns the fields of struct t by their json name
+func Fields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
@@ -0,0 +1,30 @@
+// Package testutil has the printer device and images the tests of several packages share
+package testutil
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+)
+
+// BufferDevice is a printer device that records everything sent to it
+type BufferDevice struct {
+	bytes.Buffer
+}
+
+func (d *BufferDevice) Close() error { return nil }
+
+// Checkerboard returns a width x height image with black in the top left pixel
+func Checkerboard(width, height int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if (x+y)%2 == 0 {
+				img.SetGray(x, y, color.Gray{Y: 0})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
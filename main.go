@@ -1,113 +1,56 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"image"
-	"image/color"
 	"os"
 
+	"github.com/codaea/simpleprint/server"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	"github.com/makeworld-the-better-one/dither/v2"
-	"github.com/mect/go-escpos"
 )
 
 func main() {
 
 	_ = godotenv.Overload(".env")
+	if err := server.Configure(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-	// printer setup
-	printerPath, found := os.LookupEnv("PRINTER_PATH")
-	if !found {
-		printerPath = ""
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		run, ok := commands[os.Args[1]]
+		if !ok {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		if err := run(os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			if !errors.Is(err, errProblems) {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			os.Exit(1)
+		}
+		return
 	}
-	p, err := escpos.NewUSBPrinterByPath(printerPath)
+
+	// printer setup
+	_, profile := server.ActiveProfile()
+	p, err := server.OpenPrinter(profile.URI)
 	if err != nil {
 		fmt.Println("No Printa Found!!")
 		fmt.Println("Failed to connect to printer:", err)
 		return
 	}
-	p.Init()
-	p.Smooth(true)
 
 	if os.Getenv("GIN_MODE") == "release" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	router := gin.Default()
 	router.Use(cors.Default())
-	router.Use(func(c *gin.Context) {
-		c.Set("printer", p)
-		c.Next()
-	})
-
-	router.POST("/print", handlePrint)
+	router.Use(server.UsePrinter(p))
+	server.Routes(router)
 
 	fmt.Printf("Listening and serving on 0.0.0.0:%s\n", os.Getenv("PORT"))
 	router.Run() // listen and serve on 0.0.0.0:8080 (or whatever is set as PORT environment variable)
 }
-
-func processImage(i Image) image.Image {
-
-	palette := []color.Color{
-		color.Black, color.White,
-	}
-	d := dither.NewDitherer(palette)
-
-	// Apply dither mode if specified
-	switch i.DitherMode {
-	case "floydsteinberg":
-		d.Matrix = dither.FloydSteinberg
-		return d.Dither(i.img)
-	case "none":
-		return i.img
-	default:
-		return i.img
-	}
-
-}
-
-/*
-Let's define a JSON schema for what we need
-
-{
-	"receipt": [
-		{
-			"type": "line",
-			"content": "Hello World",
-			"font-size": 2,
-			"font": "A",
-			"alignment": "center",
-			"underline": false
-		},
-		{
-			"type": "feed",
-			"lines": 2
-		},
-		{
-			"type": "barcode",
-			"code": "123456789012",
-			"barcode-type": "CODE128"
-		},
-		{
-			"type": "qr",
-			"code": "https://example.com",
-			"size": 8
-		},
-		{
-			"type": "image",
-			"data": "data:image/png;base64,iVBORw0KGgoAAAANSU...",
-			"alignment": "center",
-			"dither-mode": "floydsteinberg"
-		},
-		{
-			"type": "text",
-			"content": "Multi-line text\nwith newlines",
-			"font-size": 1,
-			"font": "A",
-			"alignment": "left",
-			"underline": false
-		}
-	]
-}
-*/
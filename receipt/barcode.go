@@ -0,0 +1,490 @@
+package receipt
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/codabar"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/code39"
+	"github.com/boombuler/barcode/code93"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/twooffive"
+)
+
+// HRIPosition is where the human readable interpretation of a barcode is printed
+type HRIPosition string
+
+const (
+	HRINone  HRIPosition = "none"
+	HRIAbove HRIPosition = "above"
+	HRIBelow HRIPosition = "below"
+	HRIBoth  HRIPosition = "both"
+)
+
+func (h *HRIPosition) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	switch s {
+	case "none", "above", "below", "both":
+		*h = HRIPosition(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid hri_position: %s. Must be none, above, below, or both", s)
+	}
+}
+
+type BarcodeType string
+
+const (
+	BarcodeUPCA                BarcodeType = "UPCA"
+	BarcodeUPCE                BarcodeType = "UPCE"
+	BarcodeEAN13               BarcodeType = "EAN13"
+	BarcodeEAN8                BarcodeType = "EAN8"
+	BarcodeCODE39              BarcodeType = "CODE39"
+	BarcodeITF                 BarcodeType = "ITF"
+	BarcodeCODABAR             BarcodeType = "CODABAR"
+	BarcodeCODE93              BarcodeType = "CODE93"
+	BarcodeCODE128             BarcodeType = "CODE128"
+	BarcodeGS1128              BarcodeType = "GS1_128"
+	BarcodeGS1DataBar          BarcodeType = "GS1_DATABAR"
+	BarcodeGS1DataBarTruncated BarcodeType = "GS1_DATABAR_TRUNCATED"
+	BarcodeGS1DataBarLimited   BarcodeType = "GS1_DATABAR_LIMITED"
+	BarcodeGS1DataBarExpanded  BarcodeType = "GS1_DATABAR_EXPANDED"
+)
+
+// BarcodeTypes is every accepted barcode type
+var BarcodeTypes = []BarcodeType{
+	BarcodeUPCA, BarcodeUPCE, BarcodeEAN13, BarcodeEAN8, BarcodeCODE39, BarcodeITF, BarcodeCODABAR,
+	BarcodeCODE93, BarcodeCODE128, BarcodeGS1128, BarcodeGS1DataBar, BarcodeGS1DataBarTruncated,
+	BarcodeGS1DataBarLimited, BarcodeGS1DataBarExpanded,
+}
+
+func (b *BarcodeType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	if !slices.Contains(BarcodeTypes, BarcodeType(s)) {
+		return fmt.Errorf("invalid barcode type: %s", s)
+	}
+	*b = BarcodeType(s)
+	return nil
+}
+
+type Barcode struct {
+	Type        string        `json:"type"`
+	Code        string        `json:"code" schema:"minLength=1"`
+	BarcodeType BarcodeType   `json:"barcode_type"`
+	Height      int           `json:"height,omitempty" schema:"minimum=1,maximum=255"`
+	ModuleWidth int           `json:"module_width,omitempty" schema:"minimum=1,maximum=6"`
+	HRIPosition HRIPosition   `json:"hri_position,omitempty"`
+	HRIFont     FontType      `json:"hri_font,omitempty" schema:"enum=A|B"`
+	Alignment   AlignmentType `json:"alignment,omitempty"`
+}
+
+// Defaults for barcode items, the height and module width go-escpos used
+const (
+	defaultBarcodeHeight      = 100
+	defaultBarcodeModuleWidth = 4
+)
+
+func (b *Barcode) UnmarshalJSON(data []byte) error {
+	// alias drops the methods so this doesn't recurse
+	type alias Barcode
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	code, err := validateBarcode(aux.Code, aux.BarcodeType)
+	if err != nil {
+		if aux.BarcodeType == "" {
+			return &fieldError{field: "barcode_type", err: err}
+		}
+		return &fieldError{field: "code", err: err}
+	}
+	aux.Code = code
+
+	if aux.Height == 0 {
+		aux.Height = defaultBarcodeHeight
+	}
+	if aux.Height < 1 || aux.Height > 255 {
+		return fieldErrorf("height", "invalid height: %d. Must be 1-255 dots", aux.Height)
+	}
+	if aux.ModuleWidth == 0 {
+		aux.ModuleWidth = defaultBarcodeModuleWidth
+	}
+	if aux.ModuleWidth < 1 || aux.ModuleWidth > 6 {
+		return fieldErrorf("module_width", "invalid module_width: %d. Must be 1-6", aux.ModuleWidth)
+	}
+	if aux.HRIPosition == "" {
+		aux.HRIPosition = HRIBelow
+	}
+	switch aux.HRIFont {
+	case "":
+		aux.HRIFont = FontA
+	case FontA, FontB:
+	default:
+		return fieldErrorf("hri_font", "invalid hri_font: %s. Must be A or B", aux.HRIFont)
+	}
+	if aux.Alignment == "" {
+		aux.Alignment = AlignCenter
+	}
+
+	*b = Barcode(aux)
+	return nil
+}
+
+// validateBarcode checks code against the rules of its barcode type and returns it
+// the way it should be sent to the printer, with missing check digits added
+func validateBarcode(code string, t BarcodeType) (string, error) {
+	if code == "" {
+		return "", fmt.Errorf("barcode code is empty")
+	}
+
+	switch t {
+	case BarcodeUPCA:
+		return withCheckDigit(code, t, 11, code)
+	case BarcodeEAN13:
+		return withCheckDigit(code, t, 12, code)
+	case BarcodeEAN8:
+		return withCheckDigit(code, t, 7, code)
+	case BarcodeUPCE:
+		return validateUPCE(code)
+	case BarcodeCODE39:
+		return validateCODE39(code)
+	case BarcodeITF:
+		if !isDigits(code) || len(code)%2 != 0 {
+			return "", fmt.Errorf("invalid ITF code %q: must be an even number of digits", code)
+		}
+		return code, nil
+	case BarcodeCODABAR:
+		return validateCODABAR(code)
+	case BarcodeCODE93:
+		return validateASCII(code, t, 0, 127)
+	case BarcodeCODE128:
+		return validateCODE128(code)
+	case BarcodeGS1128, BarcodeGS1DataBarExpanded:
+		return validateASCII(code, t, 32, 126)
+	case BarcodeGS1DataBar, BarcodeGS1DataBarTruncated, BarcodeGS1DataBarLimited:
+		return validateGS1DataBar(code, t)
+	case "":
+		return "", fmt.Errorf("barcode_type is missing")
+	}
+	return "", fmt.Errorf("invalid barcode type: %s", t)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// checkDigit computes the GS1 mod 10 check digit used by UPC and EAN codes
+func checkDigit(digits string) byte {
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i] - '0')
+		// weights alternate 3, 1 starting from the rightmost digit
+		if i%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// withCheckDigit validates a code with length digits plus a check digit, adding it if missing.
+// checked is the code the check digit is computed from, which is only different for UPC-E.
+func withCheckDigit(code string, t BarcodeType, length int, checked string) (string, error) {
+	if !isDigits(code) {
+		return "", fmt.Errorf("invalid %s code %q: must only contain digits", t, code)
+	}
+
+	switch len(code) {
+	case length:
+		return code + string(checkDigit(checked)), nil
+	case length + 1:
+		want := checkDigit(checked[:len(checked)-1])
+		if code[length] != want {
+			return "", fmt.Errorf("invalid %s code %s: check digit should be %c", t, code, want)
+		}
+		return code, nil
+	}
+	return "", fmt.Errorf("invalid %s code %q: must be %d digits, or %d with the check digit", t, code, length, length+1)
+}
+
+// validateUPCE accepts 6 digits, 7 with the number system or 8 with the check digit.
+// Only number system 0 is accepted, as that is all most printers support.
+func validateUPCE(code string) (string, error) {
+	if !isDigits(code) {
+		return "", fmt.Errorf("invalid UPCE code %q: must only contain digits", code)
+	}
+	if len(code) == 6 {
+		code = "0" + code
+	}
+	if len(code) != 7 && len(code) != 8 {
+		return "", fmt.Errorf("invalid UPCE code %q: must be 6 or 7 digits, or 8 with the check digit", code)
+	}
+	if code[0] != '0' {
+		return "", fmt.Errorf("invalid UPCE code %s: number system must be 0", code)
+	}
+
+	// the check digit of UPC-E is the one of the UPC-A code it is a compressed form of
+	upca := expandUPCE(code[:7])
+	if len(code) == 8 {
+		upca += code[7:]
+	}
+	return withCheckDigit(code, BarcodeUPCE, 7, upca)
+}
+
+// expandUPCE turns a 7 digit UPC-E code (number system and 6 digits) into its 11 digit UPC-A form
+func expandUPCE(code string) string {
+	ns, d := code[:1], code[1:]
+	switch d[5] {
+	case '0', '1', '2':
+		return ns + d[0:2] + d[5:6] + "0000" + d[2:5]
+	case '3':
+		return ns + d[0:3] + "00000" + d[3:5]
+	case '4':
+		return ns + d[0:4] + "00000" + d[4:5]
+	default:
+		return ns + d[0:5] + "0000" + d[5:6]
+	}
+}
+
+// validateASCII checks a code only has characters between min and max and fits in GS k
+func validateASCII(code string, t BarcodeType, min, max byte) (string, error) {
+	if len(code) > 255 {
+		return "", fmt.Errorf("invalid %s code %q: must be 1-255 characters", t, code)
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < min || code[i] > max {
+			return "", fmt.Errorf("invalid %s code %q: character %q at position %d is not allowed", t, code, code[i], i)
+		}
+	}
+	return code, nil
+}
+
+const codabarChars = "0123456789-$:/.+"
+
+// validateCODABAR checks a CODABAR code, A, B, C and D are the start and stop characters.
+// Codes without them are wrapped in A.
+func validateCODABAR(code string) (string, error) {
+	isStartStop := func(c byte) bool { return c >= 'A' && c <= 'D' }
+	first, last := isStartStop(code[0]), isStartStop(code[len(code)-1])
+	switch {
+	case !first && !last:
+		code = "A" + code + "A"
+	case !first || !last || len(code) < 3:
+		return "", fmt.Errorf("invalid CODABAR code %q: must start and end with A, B, C or D", code)
+	}
+
+	for i := 1; i < len(code)-1; i++ {
+		if !strings.ContainsRune(codabarChars, rune(code[i])) {
+			return "", fmt.Errorf("invalid CODABAR code %q: character %q at position %d is not allowed, only 0-9 and -$:/.+", code, code[i], i)
+		}
+	}
+	if len(code) > 255 {
+		return "", fmt.Errorf("invalid CODABAR code %q: too long", code)
+	}
+	return code, nil
+}
+
+// validateGS1DataBar checks a GTIN for the fixed length GS1 DataBar types.
+// The printer adds the check digit itself, so a given one is verified and removed.
+func validateGS1DataBar(code string, t BarcodeType) (string, error) {
+	if !isDigits(code) || (len(code) != 13 && len(code) != 14) {
+		return "", fmt.Errorf("invalid %s code %q: must be a GTIN of 13 digits, or 14 with the check digit", t, code)
+	}
+	if len(code) == 14 {
+		if want := checkDigit(code[:13]); code[13] != want {
+			return "", fmt.Errorf("invalid %s code %s: check digit should be %c", t, code, want)
+		}
+		code = code[:13]
+	}
+	if t == BarcodeGS1DataBarLimited && code[0] != '0' && code[0] != '1' {
+		return "", fmt.Errorf("invalid %s code %s: must start with 0 or 1", t, code)
+	}
+	return code, nil
+}
+
+const code39Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ -.$/+%"
+
+// validateCODE39 checks the CODE39 character set, * is only allowed as start and stop character
+func validateCODE39(code string) (string, error) {
+	data := code
+	if strings.HasPrefix(data, "*") && strings.HasSuffix(data, "*") && len(data) >= 2 {
+		data = data[1 : len(data)-1]
+	}
+	if data == "" || len(code) > 255 {
+		return "", fmt.Errorf("invalid CODE39 code %q: must be 1-255 characters", code)
+	}
+	for i, r := range data {
+		if !strings.ContainsRune(code39Chars, r) {
+			return "", fmt.Errorf("invalid CODE39 code %q: character %q at position %d is not allowed, only 0-9, A-Z (uppercase), space and -.$/+%%", code, r, i)
+		}
+	}
+	return code, nil
+}
+
+// validateCODE128 checks a CODE128 code. Codes that start with a code set ({A, {B or {C)
+// are checked against it, otherwise the code set is chosen: C for an even number of digits,
+// B for printable ASCII and A for codes with control characters.
+func validateCODE128(code string) (string, error) {
+	if !strings.HasPrefix(code, "{") {
+		code = code128Prefix(code)
+	}
+	if len(code) > 255 {
+		return "", fmt.Errorf("invalid CODE128 code %q: too long", code)
+	}
+
+	set := byte(0)
+	for i := 0; i < len(code); {
+		c := code[i]
+		if c == '{' {
+			if i+1 >= len(code) {
+				return "", fmt.Errorf("invalid CODE128 code %q: { at the end must be followed by A, B, C, S, 1-4 or {", code)
+			}
+			switch next := code[i+1]; next {
+			case 'A', 'B', 'C':
+				set = next
+			case 'S', '1', '2', '3', '4':
+				if set == 0 {
+					return "", fmt.Errorf("invalid CODE128 code %q: must start with a code set {A, {B or {C", code)
+				}
+			case '{':
+				if set != 'B' {
+					return "", fmt.Errorf("invalid CODE128 code %q: { can only be encoded in code set B", code)
+				}
+			default:
+				return "", fmt.Errorf("invalid CODE128 code %q: unknown code {%c at position %d", code, next, i)
+			}
+			i += 2
+			continue
+		}
+
+		switch set {
+		case 0:
+			return "", fmt.Errorf("invalid CODE128 code %q: must start with a code set {A, {B or {C", code)
+		case 'A':
+			if c > 95 {
+				return "", fmt.Errorf("invalid CODE128 code %q: character %q at position %d is not in code set A", code, c, i)
+			}
+		case 'B':
+			if c < 32 || c > 127 {
+				return "", fmt.Errorf("invalid CODE128 code %q: character %q at position %d is not in code set B", code, c, i)
+			}
+		case 'C':
+			if i+1 >= len(code) || !isDigits(code[i:i+2]) {
+				return "", fmt.Errorf("invalid CODE128 code %q: code set C needs pairs of digits at position %d", code, i)
+			}
+			i++
+		}
+		i++
+	}
+	return code, nil
+}
+
+// code128Prefix picks the code set for a code without one
+func code128Prefix(code string) string {
+	if isDigits(code) && len(code)%2 == 0 && len(code) >= 4 {
+		return "{C" + code
+	}
+	printable, setA := true, true
+	for i := 0; i < len(code); i++ {
+		if code[i] < 32 || code[i] > 126 {
+			printable = false
+		}
+		if code[i] > 95 {
+			setA = false
+		}
+	}
+	if printable {
+		return "{B" + strings.ReplaceAll(code, "{", "{{")
+	}
+	if setA {
+		return "{A" + code
+	}
+	// left as is, validation will point at the character that can't be encoded
+	return "{B" + code
+}
+
+// HRI is the human readable interpretation printed with the barcode, its data without the CODE128 code set and function codes
+func (b Barcode) HRI() string {
+	if b.BarcodeType != BarcodeCODE128 {
+		return b.Code
+	}
+	var hri strings.Builder
+	for i := 0; i < len(b.Code); i++ {
+		if b.Code[i] == '{' && i+1 < len(b.Code) {
+			i++
+			if b.Code[i] != '{' {
+				continue
+			}
+		}
+		hri.WriteByte(b.Code[i])
+	}
+	return hri.String()
+}
+
+// Modules is the width of the barcode in modules, so it is ModuleWidth times that in dots.
+// It is false for GS1 DataBar Expanded, whose width only the printer knows.
+func (b Barcode) Modules() (int, bool) {
+	switch b.BarcodeType {
+	case BarcodeUPCE:
+		return 51, true
+	case BarcodeGS1DataBar, BarcodeGS1DataBarTruncated:
+		return 96, true
+	case BarcodeGS1DataBarLimited:
+		return 74, true
+	case BarcodeGS1DataBarExpanded:
+		return 0, false
+	case BarcodeGS1128:
+		// the element string without the parentheses, after a FNC1 of 11 modules
+		code, err := code128.Encode(strings.NewReplacer("(", "", ")", "").Replace(b.Code))
+		if err != nil {
+			return 0, false
+		}
+		return code.Bounds().Dx() + 11, true
+	}
+	code, err := b.Encode()
+	if err != nil {
+		return 0, false
+	}
+	return code.Bounds().Dx(), true
+}
+
+// Encode draws the barcode in Go, one dot per module, for previews.
+// UPC-E, GS1-128 and the GS1 DataBar types can only be drawn by the printer.
+func (b Barcode) Encode() (barcode.Barcode, error) {
+	switch b.BarcodeType {
+	case BarcodeUPCA:
+		// UPC-A is EAN-13 with a leading 0
+		return ean.Encode("0" + b.Code)
+	case BarcodeEAN13, BarcodeEAN8:
+		return ean.Encode(b.Code)
+	case BarcodeCODE39:
+		return code39.Encode(strings.Trim(b.Code, "*"), false, false)
+	case BarcodeCODE93:
+		return code93.Encode(b.Code, false, true)
+	case BarcodeCODABAR:
+		return codabar.Encode(b.Code)
+	case BarcodeITF:
+		return twooffive.Encode(b.Code, true)
+	case BarcodeCODE128:
+		return code128.Encode(b.HRI())
+	}
+	return nil, fmt.Errorf("%s barcodes can't be drawn without the printer", b.BarcodeType)
+}
@@ -0,0 +1,126 @@
+package receipt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBarcode(t *testing.T) {
+	tests := []struct {
+		code        string
+		barcodeType BarcodeType
+		want        string
+		wantErr     string
+	}{
+		{"400638133393", BarcodeEAN13, "4006381333931", ""},
+		{"4006381333931", BarcodeEAN13, "4006381333931", ""},
+		{"4006381333932", BarcodeEAN13, "", "check digit should be 1"},
+		{"40063813339", BarcodeEAN13, "", "must be 12 digits"},
+		{"40063813339A", BarcodeEAN13, "", "only contain digits"},
+		{"03600029145", BarcodeUPCA, "036000291452", ""},
+		{"036000291452", BarcodeUPCA, "036000291452", ""},
+		{"9638507", BarcodeEAN8, "96385074", ""},
+		{"96385071", BarcodeEAN8, "", "check digit should be 4"},
+		{"425261", BarcodeUPCE, "04252614", ""},
+		{"0425261", BarcodeUPCE, "04252614", ""},
+		{"04252614", BarcodeUPCE, "04252614", ""},
+		{"14252614", BarcodeUPCE, "", "number system must be 0"},
+		{"CODE 39-$/+%.", BarcodeCODE39, "CODE 39-$/+%.", ""},
+		{"*ABC*", BarcodeCODE39, "*ABC*", ""},
+		{"abc", BarcodeCODE39, "", "is not allowed"},
+		{"A*C", BarcodeCODE39, "", "is not allowed"},
+		{"123456789012", BarcodeCODE128, "{C123456789012", ""},
+		{"12345", BarcodeCODE128, "{B12345", ""},
+		{"Hello {world}", BarcodeCODE128, "{BHello {{world}", ""},
+		{"{A\x01ABC", BarcodeCODE128, "{A\x01ABC", ""},
+		{"{C1234{BAB", BarcodeCODE128, "{C1234{BAB", ""},
+		{"{C123", BarcodeCODE128, "", "pairs of digits"},
+		{"{Aabc", BarcodeCODE128, "", "not in code set A"},
+		{"{Xabc", BarcodeCODE128, "", "unknown code"},
+		{"héllo", BarcodeCODE128, "", "not in code set B"},
+		{"", BarcodeCODE128, "", "empty"},
+		{"12345678", BarcodeITF, "12345678", ""},
+		{"1234567", BarcodeITF, "", "even number of digits"},
+		{"A40156B", BarcodeCODABAR, "A40156B", ""},
+		{"40156", BarcodeCODABAR, "A40156A", ""},
+		{"A40156", BarcodeCODABAR, "", "must start and end"},
+		{"A40E56B", BarcodeCODABAR, "", "is not allowed"},
+		{"Code 93\x01", BarcodeCODE93, "Code 93\x01", ""},
+		{"(01)09501101530003", BarcodeGS1128, "(01)09501101530003", ""},
+		{"0950110153000", BarcodeGS1DataBar, "0950110153000", ""},
+		{"09501101530003", BarcodeGS1DataBarTruncated, "0950110153000", ""},
+		{"09501101530004", BarcodeGS1DataBar, "", "check digit should be 3"},
+		{"2950110153000", BarcodeGS1DataBarLimited, "", "must start with 0 or 1"},
+		{"123", "PDF", "", "invalid barcode type"},
+	}
+
+	for _, tt := range tests {
+		got, err := validateBarcode(tt.code, tt.barcodeType)
+		if tt.wantErr != "" {
+			assert.ErrorContains(t, err, tt.wantErr, "%s %q", tt.barcodeType, tt.code)
+			continue
+		}
+		assert.NoError(t, err, "%s %q", tt.barcodeType, tt.code)
+		assert.Equal(t, tt.want, got, "%s %q", tt.barcodeType, tt.code)
+	}
+}
+
+func TestBarcode_InvalidLayout(t *testing.T) {
+	tests := map[string]string{
+		`"height": 300`:         "invalid height",
+		`"module_width": 7`:     "invalid module_width",
+		`"hri_position": "top"`: "invalid hri_position",
+		`"hri_font": "C"`:       "invalid hri_font",
+		`"alignment": "middle"`: "invalid alignment",
+	}
+	for field, wantErr := range tests {
+		var b Barcode
+		err := json.Unmarshal([]byte(`{"code": "123", "barcode_type": "CODE39", `+field+`}`), &b)
+		assert.ErrorContains(t, err, wantErr, field)
+	}
+}
+
+func TestBarcode_Encode(t *testing.T) {
+	for _, code := range []string{
+		`{"code": "03600029145", "barcode_type": "UPCA"}`,
+		`{"code": "400638133393", "barcode_type": "EAN13"}`,
+		`{"code": "*ABC*", "barcode_type": "CODE39"}`,
+		`{"code": "Hello {world}", "barcode_type": "CODE128"}`,
+		`{"code": "12345678", "barcode_type": "ITF"}`,
+		`{"code": "A40156B", "barcode_type": "CODABAR"}`,
+	} {
+		var b Barcode
+		assert.NoError(t, json.Unmarshal([]byte(code), &b))
+		encoded, err := b.Encode()
+		if assert.NoError(t, err, code) {
+			assert.Greater(t, encoded.Bounds().Dx(), 0, code)
+		}
+	}
+
+	_, err := Barcode{Code: "0950110153000", BarcodeType: BarcodeGS1DataBar}.Encode()
+	assert.ErrorContains(t, err, "can't be drawn")
+}
+
+func TestBarcode_HRI(t *testing.T) {
+	assert.Equal(t, "Hello {world}", Barcode{Code: "{BHello {{world}", BarcodeType: BarcodeCODE128}.HRI())
+	assert.Equal(t, "1234AB", Barcode{Code: "{C1234{BAB", BarcodeType: BarcodeCODE128}.HRI())
+	assert.Equal(t, "4006381333931", Barcode{Code: "4006381333931", BarcodeType: BarcodeEAN13}.HRI())
+}
+
+func TestBarcode_Modules(t *testing.T) {
+	for b, want := range map[Barcode]int{
+		{Code: "4006381333931", BarcodeType: BarcodeEAN13}:       95,
+		{Code: "01234565", BarcodeType: BarcodeUPCE}:             51,
+		{Code: "0950110153000", BarcodeType: BarcodeGS1DataBar}:  96,
+		{Code: "(01)09501101530008", BarcodeType: BarcodeGS1128}: 134, // start C, FNC1, 8 pairs of digits, check and stop
+	} {
+		modules, ok := b.Modules()
+		assert.True(t, ok, b.BarcodeType)
+		assert.Equal(t, want, modules, b.BarcodeType)
+	}
+
+	_, ok := Barcode{Code: "(01)09501101530008", BarcodeType: BarcodeGS1DataBarExpanded}.Modules()
+	assert.False(t, ok)
+}
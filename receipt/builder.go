@@ -0,0 +1,175 @@
+package receipt
+
+import (
+	"maps"
+	"slices"
+)
+
+// Option styles an item added with a Builder. Options that don't apply to an item are ignored,
+// so a font has no effect on a QR code.
+type Option func(*style)
+
+type style struct {
+	font      FontType
+	size      int
+	alignment AlignmentType
+	bold      bool
+	underline bool
+	codePage  CodePage
+}
+
+var (
+	Bold      Option = func(s *style) { s.bold = true }
+	Underline Option = func(s *style) { s.underline = true }
+	Left      Option = func(s *style) { s.alignment = AlignLeft }
+	Center    Option = func(s *style) { s.alignment = AlignCenter }
+	Right     Option = func(s *style) { s.alignment = AlignRight }
+)
+
+// Font prints text in font f
+func Font(f FontType) Option {
+	return func(s *style) { s.font = f }
+}
+
+// Size is the font size multiplier of text, or the module size of a 2D code
+func Size(n int) Option {
+	return func(s *style) { s.size = n }
+}
+
+// Encoding prints text in code page c
+func Encoding(c CodePage) Option {
+	return func(s *style) { s.codePage = c }
+}
+
+func newStyle(opts []Option) style {
+	var s style
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// Builder builds a Request one item at a time:
+//
+//	req := receipt.New().
+//		Line("Coffee Shop", receipt.Bold, receipt.Center, receipt.Size(2)).
+//		Feed(1).
+//		QR("https://example.com/r/123", receipt.Center).
+//		Build()
+type Builder struct {
+	req Request
+}
+
+func New() *Builder {
+	return &Builder{}
+}
+
+// Add adds any item, for the fields the other methods don't set
+func (b *Builder) Add(items ...Item) *Builder {
+	b.req.Receipt = append(b.req.Receipt, items...)
+	return b
+}
+
+// Line prints content on a line of its own
+func (b *Builder) Line(content string, opts ...Option) *Builder {
+	s := newStyle(opts)
+	return b.Add(Line{Content: content, FontSize: s.size, Font: s.font, Alignment: s.alignment, Bold: s.bold, Underline: s.underline, CodePage: s.codePage})
+}
+
+// Text prints content without a line break, so the next text continues the line
+func (b *Builder) Text(content string, opts ...Option) *Builder {
+	s := newStyle(opts)
+	return b.Add(Text{Content: content, FontSize: s.size, Font: s.font, Alignment: s.alignment, Bold: s.bold, Underline: s.underline, CodePage: s.codePage})
+}
+
+// Columns prints the cells side by side, Col makes a cell
+func (b *Builder) Columns(cells []Column, opts ...Option) *Builder {
+	s := newStyle(opts)
+	return b.Add(Columns{Columns: cells, FontSize: s.size, Font: s.font, Bold: s.bold})
+}
+
+// Col is a cell of a columns item, a width of 0 shares the space the other columns leave
+func Col(content string, width int, alignment AlignmentType) Column {
+	return Column{Content: content, Width: width, Alignment: alignment}
+}
+
+func (b *Builder) Feed(lines int) *Builder {
+	return b.Add(Feed{Lines: lines})
+}
+
+// Cut cuts the paper in the middle of the receipt, the end of the receipt is cut by FinalCut
+func (b *Builder) Cut(mode CutMode) *Builder {
+	return b.Add(Cut{Mode: mode})
+}
+
+func (b *Builder) Barcode(code string, t BarcodeType, opts ...Option) *Builder {
+	s := newStyle(opts)
+	return b.Add(Barcode{Code: code, BarcodeType: t, Alignment: s.alignment})
+}
+
+func (b *Builder) QR(code string, opts ...Option) *Builder {
+	s := newStyle(opts)
+	return b.Add(QRCode{Code: code, Size: s.size, Alignment: s.alignment})
+}
+
+// Image prints an image from a base64 data URI
+func (b *Builder) Image(dataURI string, opts ...Option) *Builder {
+	s := newStyle(opts)
+	return b.Add(Image{Data: dataURI, Alignment: s.alignment})
+}
+
+// Asset prints an image stored on the server under name
+func (b *Builder) Asset(name string, opts ...Option) *Builder {
+	s := newStyle(opts)
+	return b.Add(Image{Asset: name, Alignment: s.alignment})
+}
+
+// NVImage prints an image stored in the printer under key
+func (b *Builder) NVImage(key string, opts ...Option) *Builder {
+	s := newStyle(opts)
+	return b.Add(NVImage{Key: key, Alignment: s.alignment})
+}
+
+// OpenDrawer kicks the cash drawer on pin 2
+func (b *Builder) OpenDrawer() *Builder {
+	return b.Add(Drawer{})
+}
+
+func (b *Builder) Beep(count int) *Builder {
+	return b.Add(Beep{Count: count})
+}
+
+// FinalCut sets how the paper is cut after the receipt
+func (b *Builder) FinalCut(mode CutMode) *Builder {
+	b.req.Cut = mode
+	return b
+}
+
+// Copies prints the receipt n times, each copy cut separately
+func (b *Builder) Copies(n int) *Builder {
+	b.req.Copies = n
+	return b
+}
+
+// Variable replaces {{name}} with value in the text of every copy
+func (b *Builder) Variable(name, value string) *Builder {
+	if b.req.Variables == nil {
+		b.req.Variables = make(map[string]string)
+	}
+	b.req.Variables[name] = value
+	return b
+}
+
+// CopyVariables sets the variables of each copy, on top of the shared ones
+func (b *Builder) CopyVariables(vars ...map[string]string) *Builder {
+	b.req.CopyVariables = vars
+	return b
+}
+
+// Build returns the request, the builder can be used again to add more items to a copy of it
+func (b *Builder) Build() Request {
+	req := b.req
+	req.Receipt = slices.Clone(b.req.Receipt)
+	req.Variables = maps.Clone(b.req.Variables)
+	return req
+}
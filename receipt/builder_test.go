@@ -0,0 +1,58 @@
+package receipt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder_JSON(t *testing.T) {
+	req := New().
+		Line("Hi", Bold, Center, Size(2)).
+		Text("a", Font(FontB)).
+		Columns([]Column{Col("Coffee", 0, AlignLeft), Col("$3.00", 8, AlignRight)}, Bold).
+		Feed(2).
+		QR("https://example.com", Center, Size(6)).
+		Barcode("123456789012", BarcodeUPCA).
+		Cut(CutPartial).
+		OpenDrawer().
+		FinalCut(CutNone).
+		Copies(2).
+		Variable("name", "Ann").
+		Build()
+
+	data, err := json.Marshal(req)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"receipt": [
+			{"type": "line", "content": "Hi", "font_size": 2, "alignment": "center", "bold": true},
+			{"type": "text", "content": "a", "font": "B"},
+			{"type": "columns", "columns": [{"content": "Coffee", "alignment": "left"}, {"content": "$3.00", "width": 8, "alignment": "right"}], "bold": true},
+			{"type": "feed", "lines": 2},
+			{"type": "qr", "code": "https://example.com", "size": 6, "alignment": "center"},
+			{"type": "barcode", "code": "123456789012", "barcode_type": "UPCA"},
+			{"type": "cut", "mode": "partial"},
+			{"type": "drawer"}
+		],
+		"cut": "none",
+		"copies": 2,
+		"variables": {"name": "Ann"}
+	}`, string(data))
+}
+
+func TestBuilder_BuildCopies(t *testing.T) {
+	b := New().Line("first").Variable("a", "1")
+	first := b.Build()
+	b.Line("second").Variable("a", "2")
+
+	assert.Len(t, first.Receipt, 1)
+	assert.Equal(t, "1", first.Variables["a"])
+	assert.Len(t, b.Build().Receipt, 2)
+}
+
+func TestItem_TypeIsAlwaysSet(t *testing.T) {
+	data, err := json.Marshal(Image{Type: "wrong", Asset: "logo"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type": "image", "asset": "logo"}`, string(data))
+}
@@ -0,0 +1,107 @@
+package receipt
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// CodePage is the character code table text is printed in. Text is encoded into it,
+// characters the table doesn't have are printed as ?.
+type CodePage string
+
+const (
+	CodePagePC437   CodePage = "PC437"
+	CodePagePC850   CodePage = "PC850"
+	CodePagePC852   CodePage = "PC852"
+	CodePagePC858   CodePage = "PC858"
+	CodePagePC860   CodePage = "PC860"
+	CodePagePC863   CodePage = "PC863"
+	CodePagePC865   CodePage = "PC865"
+	CodePagePC866   CodePage = "PC866"
+	CodePageWPC1252 CodePage = "WPC1252"
+)
+
+// CodePages is every accepted code page
+var CodePages = []CodePage{
+	CodePagePC437, CodePagePC850, CodePagePC852, CodePagePC858, CodePagePC860,
+	CodePagePC863, CodePagePC865, CodePagePC866, CodePageWPC1252,
+}
+
+var charmaps = map[CodePage]*charmap.Charmap{
+	CodePagePC437:   charmap.CodePage437,
+	CodePagePC850:   charmap.CodePage850,
+	CodePagePC852:   charmap.CodePage852,
+	CodePagePC858:   charmap.CodePage858,
+	CodePagePC860:   charmap.CodePage860,
+	CodePagePC863:   charmap.CodePage863,
+	CodePagePC865:   charmap.CodePage865,
+	CodePagePC866:   charmap.CodePage866,
+	CodePageWPC1252: charmap.Windows1252,
+}
+
+func (c *CodePage) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	page := CodePage(s)
+	if !slices.Contains(CodePages, page) {
+		names := make([]string, len(CodePages))
+		for i, p := range CodePages {
+			names[i] = string(p)
+		}
+		return fmt.Errorf("invalid code_page: %s. Must be one of %s", s, strings.Join(names, ", "))
+	}
+	*c = page
+	return nil
+}
+
+// Encode encodes text into the code page
+func (c CodePage) Encode(text string) []byte {
+	cm := charmaps[c]
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r < 0x80 {
+			out = append(out, byte(r))
+		} else if b, ok := cm.EncodeRune(r); ok {
+			out = append(out, b)
+		} else {
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// Printable returns text the way the code page prints it, with ? for the characters it doesn't have
+func (c CodePage) Printable(text string) string {
+	cm := charmaps[c]
+	var sb strings.Builder
+	for _, b := range c.Encode(text) {
+		if b < 0x80 {
+			sb.WriteByte(b)
+		} else {
+			sb.WriteRune(cm.DecodeByte(b))
+		}
+	}
+	return sb.String()
+}
+
+// Glyphs returns the characters of the upper half of the table, 0x80 to 0xFF, which is where code pages differ.
+// Codes the table leaves empty are spaces.
+func (c CodePage) Glyphs() []rune {
+	cm := charmaps[c]
+	glyphs := make([]rune, 0x80)
+	for i := range glyphs {
+		glyphs[i] = cm.DecodeByte(byte(0x80 + i))
+		if glyphs[i] == utf8.RuneError {
+			glyphs[i] = ' '
+		}
+	}
+	return glyphs
+}
@@ -0,0 +1,25 @@
+package receipt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodePage(t *testing.T) {
+	var l Line
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "line", "content": "Café", "code_page": "PC850"}`), &l))
+	assert.Equal(t, CodePagePC850, l.CodePage)
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"type": "line", "content": "x", "code_page": "PC999"}`), &l), "invalid code_page")
+
+	assert.Equal(t, []byte{'C', 'a', 'f', 0x82, '?'}, CodePagePC850.Encode("Café€"))
+	assert.Equal(t, "Café€", CodePagePC858.Printable("Café€"))
+	assert.Equal(t, "Caf??", CodePagePC866.Printable("Café€"))
+
+	glyphs := CodePageWPC1252.Glyphs()
+	assert.Len(t, glyphs, 128)
+	assert.Equal(t, '€', glyphs[0])
+	// 0x81 isn't used in Windows-1252
+	assert.Equal(t, ' ', glyphs[1])
+}
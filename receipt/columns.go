@@ -0,0 +1,165 @@
+package receipt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Column is one cell of a columns item, Width is in characters.
+// Columns with a width of 0 share the space the others leave.
+type Column struct {
+	Content   string        `json:"content"`
+	Width     int           `json:"width,omitempty" schema:"minimum=0"`
+	Alignment AlignmentType `json:"alignment,omitempty"`
+}
+
+// Columns prints cells side by side on one line, like a table row.
+// Cells longer than their column are cut off.
+type Columns struct {
+	Type     string   `json:"type"`
+	Columns  []Column `json:"columns" schema:"minItems=1"`
+	FontSize int      `json:"font_size,omitempty" schema:"minimum=1,maximum=8"`
+	Font     FontType `json:"font,omitempty"`
+	Bold     bool     `json:"bold,omitempty"`
+}
+
+// ColumnGap is the number of spaces between columns
+const ColumnGap = 1
+
+// FontColumns is the characters per line of fonts at size 1, for printers whose fonts aren't
+// the usual widths. Fonts that aren't in it are worked out from PaperWidth.
+var FontColumns = map[FontType]int{}
+
+// CharWidth is the width in dots of a character of font at size.
+// Font A is 12 dots wide, B and C are 9, unless FontColumns has the font.
+func CharWidth(font FontType, size int) int {
+	if font == "" {
+		font = FontA
+	}
+	dots := 12
+	if columns := FontColumns[font]; columns > 0 {
+		dots = max(1, PaperWidth/columns)
+	} else if font == FontB || font == FontC {
+		dots = 9
+	}
+	return dots * max(1, size)
+}
+
+// CharsPerLine is how many characters of font at size fit on the paper
+func CharsPerLine(font FontType, size int) int {
+	if font == "" {
+		font = FontA
+	}
+	if columns := FontColumns[font]; columns > 0 {
+		return columns / max(1, size)
+	}
+	return PaperWidth / CharWidth(font, size)
+}
+
+func (c *Columns) UnmarshalJSON(data []byte) error {
+	type alias Columns
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if len(aux.Columns) == 0 {
+		return fieldErrorf("columns", "columns is empty")
+	}
+	applyDefaultStyle(&aux.Font, &aux.FontSize, nil, nil)
+	if err := checkFontSize(&aux.FontSize); err != nil {
+		return err
+	}
+	if aux.Font == "" {
+		aux.Font = FontA
+	}
+
+	width := CharsPerLine(aux.Font, aux.FontSize)
+	used, shared := ColumnGap*(len(aux.Columns)-1), 0
+	for i, col := range aux.Columns {
+		if col.Width < 0 {
+			return fieldErrorf(fmt.Sprintf("columns/%d/width", i), "invalid width of column %d: %d", i, col.Width)
+		}
+		if col.Width == 0 {
+			shared++
+		}
+		if col.Alignment == "" {
+			aux.Columns[i].Alignment = AlignLeft
+		}
+		used += col.Width
+	}
+	if used+shared > width {
+		return fieldErrorf("columns", "columns are %d characters wide, only %d fit on a line", used+shared, width)
+	}
+
+	*c = Columns(aux)
+	return nil
+}
+
+// Widths returns the width of every column, sharing the free space between the columns without a width
+func (c Columns) Widths() []int {
+	free := CharsPerLine(c.Font, c.FontSize) - ColumnGap*(len(c.Columns)-1)
+	shared := 0
+	for _, col := range c.Columns {
+		free -= col.Width
+		if col.Width == 0 {
+			shared++
+		}
+	}
+
+	widths := make([]int, len(c.Columns))
+	extra := 0
+	if shared > 0 {
+		// the first shared column gets what doesn't divide evenly
+		extra = free % shared
+	}
+	for i, col := range c.Columns {
+		widths[i] = col.Width
+		if col.Width == 0 {
+			widths[i] = free/shared + extra
+			extra = 0
+		}
+	}
+	return widths
+}
+
+// Layout lays the columns out as one line of text
+func (c Columns) Layout() string {
+	var line strings.Builder
+	for i, width := range c.Widths() {
+		if i > 0 {
+			line.WriteString(strings.Repeat(" ", ColumnGap))
+		}
+		line.WriteString(fitColumn(c.Columns[i].Content, width, c.Columns[i].Alignment))
+	}
+	return strings.TrimRight(line.String(), " ")
+}
+
+// Truncated returns the index of every column whose content is cut off
+func (c Columns) Truncated() []int {
+	var cut []int
+	for i, width := range c.Widths() {
+		if utf8.RuneCountInString(c.Columns[i].Content) > width {
+			cut = append(cut, i)
+		}
+	}
+	return cut
+}
+
+// fitColumn cuts off or pads s to exactly width characters
+func fitColumn(s string, width int, alignment AlignmentType) string {
+	runes := []rune(s)
+	if len(runes) >= width {
+		return string(runes[:width])
+	}
+	n := width - len(runes)
+	switch alignment {
+	case AlignRight:
+		return strings.Repeat(" ", n) + s
+	case AlignCenter:
+		return strings.Repeat(" ", n/2) + s + strings.Repeat(" ", n-n/2)
+	}
+	return s + strings.Repeat(" ", n)
+}
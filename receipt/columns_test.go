@@ -0,0 +1,47 @@
+package receipt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumns_Layout(t *testing.T) {
+	var req Request
+	err := json.Unmarshal([]byte(`{"receipt": [{"type": "columns", "columns": [
+		{"content": "Cappuccino with oat milk"},
+		{"content": "2", "width": 3, "alignment": "right"},
+		{"content": "$9.00", "width": 8, "alignment": "right"}
+	]}]}`), &req)
+	assert.NoError(t, err)
+
+	cols := req.Receipt[0].(Columns)
+	assert.Equal(t, 1, cols.FontSize)
+	assert.Equal(t, []int{35, 3, 8}, cols.Widths(), "the column without a width gets the rest of the 48 characters")
+	assert.Equal(t, "Cappuccino with oat milk              2    $9.00", cols.Layout())
+}
+
+func TestColumns_SharedAndTruncated(t *testing.T) {
+	cols := Columns{Columns: []Column{{Content: "abcdefghijklmnopqrstuvwxyz", Alignment: AlignLeft}, {Content: "mid", Alignment: AlignCenter}}, FontSize: 4, Font: FontA}
+	assert.Equal(t, []int{6, 5}, cols.Widths(), "12 characters at size 4, minus the gap")
+	assert.Equal(t, "abcdef  mid", cols.Layout())
+}
+
+func TestColumns_TooWide(t *testing.T) {
+	var req Request
+	err := json.Unmarshal([]byte(`{"receipt": [{"type": "columns", "font": "B", "columns": [{"content": "a", "width": 40}, {"content": "b", "width": 30}]}]}`), &req)
+	assert.ErrorContains(t, err, "only 64 fit on a line")
+}
+
+func TestCharsPerLine_FontColumns(t *testing.T) {
+	assert.Equal(t, 48, CharsPerLine(FontA, 1))
+	assert.Equal(t, 64, CharsPerLine(FontB, 1))
+
+	FontColumns = map[FontType]int{FontB: 42}
+	defer func() { FontColumns = map[FontType]int{} }()
+	assert.Equal(t, 42, CharsPerLine(FontB, 1))
+	assert.Equal(t, 21, CharsPerLine(FontB, 2))
+	assert.Equal(t, 13, CharWidth(FontB, 1))
+	assert.Equal(t, 48, CharsPerLine(FontA, 1))
+}
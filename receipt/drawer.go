@@ -0,0 +1,100 @@
+package receipt
+
+import "encoding/json"
+
+// BeepMethod is the command used for the buzzer, printers support one or the other
+type BeepMethod string
+
+const (
+	BeepESCB      BeepMethod = "esc_b"
+	BeepESCParenA BeepMethod = "esc_paren_a"
+)
+
+// Drawer kicks the cash drawer connected to the printer
+type Drawer struct {
+	Type  string `json:"type"`
+	Pin   int    `json:"pin,omitempty" schema:"enum=2|5"`
+	OnMs  int    `json:"on_ms,omitempty" schema:"minimum=2,maximum=510"`
+	OffMs int    `json:"off_ms,omitempty" schema:"minimum=2,maximum=510"`
+}
+
+func (d *Drawer) UnmarshalJSON(data []byte) error {
+	type alias Drawer
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if aux.Pin == 0 {
+		aux.Pin = 2
+	}
+	if aux.Pin != 2 && aux.Pin != 5 {
+		return fieldErrorf("pin", "invalid pin: %d. Must be 2 or 5", aux.Pin)
+	}
+	if aux.OnMs == 0 {
+		aux.OnMs = 100
+	}
+	if aux.OffMs == 0 {
+		aux.OffMs = 500
+	}
+	// ESC p times are in units of 2ms, up to 255 units
+	if aux.OnMs < 2 || aux.OnMs > 510 {
+		return fieldErrorf("on_ms", "invalid on_ms: %d. Must be 2-510", aux.OnMs)
+	}
+	if aux.OffMs < 2 || aux.OffMs > 510 {
+		return fieldErrorf("off_ms", "invalid off_ms: %d. Must be 2-510", aux.OffMs)
+	}
+
+	*d = Drawer(aux)
+	return nil
+}
+
+// Beep sounds the printer's buzzer
+type Beep struct {
+	Type       string     `json:"type"`
+	Count      int        `json:"count,omitempty" schema:"minimum=1,maximum=63"`
+	DurationMs int        `json:"duration_ms,omitempty" schema:"minimum=50,maximum=25500"`
+	Method     BeepMethod `json:"method,omitempty"`
+}
+
+func (b *Beep) UnmarshalJSON(data []byte) error {
+	type alias Beep
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if aux.Method == "" {
+		aux.Method = BeepESCB
+	}
+	if aux.Count == 0 {
+		aux.Count = 1
+	}
+	if aux.DurationMs == 0 {
+		aux.DurationMs = 100
+	}
+
+	switch aux.Method {
+	case BeepESCB:
+		// ESC B beeps 1-9 times for 1-9 units of 50ms
+		if aux.Count < 1 || aux.Count > 9 {
+			return fieldErrorf("count", "invalid count: %d. Must be 1-9", aux.Count)
+		}
+		if aux.DurationMs < 50 || aux.DurationMs > 450 {
+			return fieldErrorf("duration_ms", "invalid duration_ms: %d. Must be 50-450", aux.DurationMs)
+		}
+	case BeepESCParenA:
+		// ESC ( A beeps 1-63 times for 1-255 units of 100ms
+		if aux.Count < 1 || aux.Count > 63 {
+			return fieldErrorf("count", "invalid count: %d. Must be 1-63", aux.Count)
+		}
+		if aux.DurationMs < 100 || aux.DurationMs > 25500 {
+			return fieldErrorf("duration_ms", "invalid duration_ms: %d. Must be 100-25500", aux.DurationMs)
+		}
+	default:
+		return fieldErrorf("method", "invalid method: %s. Must be esc_b or esc_paren_a", aux.Method)
+	}
+
+	*b = Beep(aux)
+	return nil
+}
@@ -0,0 +1,129 @@
+package receipt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"regexp"
+	"strings"
+
+	"github.com/codaea/simpleprint/imaging"
+)
+
+// DitherMode is how images are turned into black and white
+type DitherMode string
+
+const (
+	DitherNone           DitherMode = "none"
+	DitherFloydSteinberg DitherMode = "floydsteinberg"
+)
+
+// Image is either Data, a base64 data URI, or Asset, the name of a stored asset.
+// Img is the decoded image, an image item built in Go can set it instead of Data.
+type Image struct {
+	Type       string        `json:"type"`
+	Data       string        `json:"data,omitempty"`
+	Asset      string        `json:"asset,omitempty"`
+	Alignment  AlignmentType `json:"alignment,omitempty"`
+	DitherMode DitherMode    `json:"dither_mode,omitempty" schema:"enum=none|floydsteinberg"`
+	Width      int           `json:"width,omitempty" schema:"minimum=0"`
+	Img        image.Image   `json:"-"`
+}
+
+func (i *Image) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Data       string        `json:"data"`
+		Asset      string        `json:"asset"`
+		DitherMode string        `json:"dither_mode"`
+		Alignment  AlignmentType `json:"alignment"`
+		Width      int           `json:"width"`
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	i.Data = aux.Data
+	i.Asset = aux.Asset
+	i.DitherMode = DitherMode(strings.ToLower(aux.DitherMode))
+	i.Alignment = aux.Alignment
+	i.Width = aux.Width
+
+	switch i.DitherMode {
+	case "", DitherNone, DitherFloydSteinberg:
+	default:
+		return fieldErrorf("dither_mode", "invalid dither_mode: %s. Must be none or floydsteinberg", aux.DitherMode)
+	}
+
+	switch {
+	case aux.Asset != "" && aux.Data != "":
+		return fieldErrorf("asset", "image can have either data or asset, not both")
+	case aux.Asset != "":
+		// assets are already scaled and dithered when they are uploaded
+		img, err := loadAsset(aux.Asset)
+		if err != nil {
+			return &fieldError{field: "asset", err: err}
+		}
+		i.Img = img
+		return nil
+	}
+
+	img, err := imaging.Decode(aux.Data)
+	if err != nil {
+		return &fieldError{field: "data", err: err}
+	}
+
+	i.Img = img
+	return nil
+
+}
+
+// Bitmap is the image the way it is printed, scaled to its width and the paper and dithered
+func (i Image) Bitmap() image.Image {
+	img := imaging.Scale(i.Img, i.Width, PaperWidth)
+	if i.DitherMode == DitherFloydSteinberg {
+		return imaging.Dither(img)
+	}
+	return img
+}
+
+// ErrInvalidNVKey is the error of a key that can't be used for an NV graphic
+var ErrInvalidNVKey = errors.New("invalid NV key")
+
+var nvKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]{2}$`)
+
+// CheckNVKey returns an error if key isn't 2 letters or digits, the key codes of NV graphics
+func CheckNVKey(key string) error {
+	if !nvKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: %s. Must be 2 letters or digits", ErrInvalidNVKey, key)
+	}
+	return nil
+}
+
+// NVImage prints a graphic stored in the printer's NV memory by its key code
+type NVImage struct {
+	Type         string        `json:"type"`
+	Key          string        `json:"key" schema:"pattern=^[A-Za-z0-9]{2}$"`
+	Alignment    AlignmentType `json:"alignment,omitempty"`
+	DoubleWidth  bool          `json:"double_width,omitempty"`
+	DoubleHeight bool          `json:"double_height,omitempty"`
+}
+
+// UnmarshalJSON only accepts keys CheckNVGraphic accepts
+func (n *NVImage) UnmarshalJSON(data []byte) error {
+	type alias NVImage
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	err := CheckNVKey(aux.Key)
+	if err == nil && CheckNVGraphic != nil {
+		err = CheckNVGraphic(aux.Key)
+	}
+	if err != nil {
+		return &fieldError{field: "key", err: err}
+	}
+	*n = NVImage(aux)
+	return nil
+}
@@ -0,0 +1,236 @@
+package receipt
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type FontType string
+
+const (
+	FontA FontType = "A"
+	FontB FontType = "B"
+	FontC FontType = "C"
+)
+
+func (f *FontType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	switch s {
+	case "A", "B", "C":
+		*f = FontType(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid font: %s. Must be A, B, or C", s)
+	}
+}
+
+type AlignmentType string
+
+const (
+	AlignLeft   AlignmentType = "left"
+	AlignRight  AlignmentType = "right"
+	AlignCenter AlignmentType = "center"
+)
+
+func (a *AlignmentType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	alignment, err := ParseAlignment(s)
+	if err != nil {
+		return err
+	}
+	*a = alignment
+	return nil
+}
+
+func ParseAlignment(s string) (AlignmentType, error) {
+	switch s {
+	case "left", "right", "center":
+		return AlignmentType(s), nil
+	default:
+		return "", fmt.Errorf("invalid alignment: %s. Must be left, right, or center", s)
+	}
+}
+
+type CutMode string
+
+const (
+	CutFull    CutMode = "full"
+	CutPartial CutMode = "partial"
+	CutNone    CutMode = "none"
+)
+
+func (m *CutMode) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	switch s {
+	case "full", "partial", "none":
+		*m = CutMode(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid cut: %s. Must be full, partial, or none", s)
+	}
+}
+
+// MaxFontSize is the largest character size of GS !
+const MaxFontSize = 8
+
+// checkFontSize validates font_size, 0 is replaced by the default of 1
+func checkFontSize(size *int) error {
+	if *size == 0 {
+		*size = 1
+	}
+	if *size < 1 || *size > MaxFontSize {
+		return fieldErrorf("font_size", "invalid font_size: %d. Must be 1-%d", *size, MaxFontSize)
+	}
+	return nil
+}
+
+// TextStyle is a style of text, the default style of line, text and columns items
+type TextStyle struct {
+	Font      FontType      `json:"font,omitempty"`
+	FontSize  int           `json:"font_size,omitempty"`
+	Alignment AlignmentType `json:"alignment,omitempty"`
+}
+
+// DefaultStyle is the style of the fields text items leave out, set by the printer's profile
+var DefaultStyle TextStyle
+
+// DefaultCodePage is the code page of text items that don't set one, text is sent as ISO-8859-15 if it is empty
+var DefaultCodePage CodePage
+
+// WithDefaults fills in the fields s leaves out like decoding a text item does,
+// and then with font A at size 1 on the left, for text built in Go
+func (s TextStyle) WithDefaults() TextStyle {
+	applyDefaultStyle(&s.Font, &s.FontSize, &s.Alignment, nil)
+	if s.Font == "" {
+		s.Font = FontA
+	}
+	if s.FontSize == 0 {
+		s.FontSize = 1
+	}
+	if s.Alignment == "" {
+		s.Alignment = AlignLeft
+	}
+	return s
+}
+
+// applyDefaultStyle fills in the style fields an item leaves out, the ones it doesn't have are nil
+func applyDefaultStyle(font *FontType, size *int, alignment *AlignmentType, codePage *CodePage) {
+	if *font == "" {
+		*font = DefaultStyle.Font
+	}
+	if *size == 0 {
+		*size = DefaultStyle.FontSize
+	}
+	if alignment != nil && *alignment == "" {
+		*alignment = DefaultStyle.Alignment
+	}
+	if codePage != nil && *codePage == "" {
+		*codePage = DefaultCodePage
+	}
+}
+
+// Zero values of the optional fields are left out when an item is marshaled,
+// so the server uses its defaults for them
+
+type Line struct {
+	Type      string        `json:"type"`
+	Content   string        `json:"content"`
+	FontSize  int           `json:"font_size,omitempty" schema:"minimum=1,maximum=8"`
+	Font      FontType      `json:"font,omitempty"`
+	Alignment AlignmentType `json:"alignment,omitempty"`
+	Underline bool          `json:"underline,omitempty"`
+	Bold      bool          `json:"bold,omitempty"`
+	// CodePage is the table the text is encoded in and selected with ESC t, DefaultCodePage if empty
+	CodePage CodePage `json:"code_page,omitempty"`
+}
+
+func (l *Line) UnmarshalJSON(data []byte) error {
+	type alias Line
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	applyDefaultStyle(&aux.Font, &aux.FontSize, &aux.Alignment, &aux.CodePage)
+	if err := checkFontSize(&aux.FontSize); err != nil {
+		return err
+	}
+	*l = Line(aux)
+	return nil
+}
+
+// Text is printed without a line break after it, so consecutive texts share a line
+type Text struct {
+	Type      string        `json:"type"`
+	Content   string        `json:"content"`
+	FontSize  int           `json:"font_size,omitempty" schema:"minimum=1,maximum=8"`
+	Font      FontType      `json:"font,omitempty"`
+	Alignment AlignmentType `json:"alignment,omitempty"`
+	Underline bool          `json:"underline,omitempty"`
+	Bold      bool          `json:"bold,omitempty"`
+	// CodePage is the table the text is encoded in and selected with ESC t, DefaultCodePage if empty
+	CodePage CodePage `json:"code_page,omitempty"`
+}
+
+func (t *Text) UnmarshalJSON(data []byte) error {
+	type alias Text
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	applyDefaultStyle(&aux.Font, &aux.FontSize, &aux.Alignment, &aux.CodePage)
+	if err := checkFontSize(&aux.FontSize); err != nil {
+		return err
+	}
+	*t = Text(aux)
+	return nil
+}
+
+type Feed struct {
+	Type  string `json:"type"`
+	Lines int    `json:"lines" schema:"minimum=0,maximum=255"`
+}
+
+func (f *Feed) UnmarshalJSON(data []byte) error {
+	type alias Feed
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	// ESC d feeds up to 255 lines
+	if aux.Lines < 0 || aux.Lines > 255 {
+		return fieldErrorf("lines", "invalid lines: %d. Must be 0-255", aux.Lines)
+	}
+	*f = Feed(aux)
+	return nil
+}
+
+// Cut cuts the paper in the middle of a job, for example between labels
+type Cut struct {
+	Type string  `json:"type"`
+	Mode CutMode `json:"mode,omitempty"`
+}
+
+func (c *Cut) UnmarshalJSON(data []byte) error {
+	type alias Cut
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Mode == "" {
+		aux.Mode = CutFull
+	}
+	*c = Cut(aux)
+	return nil
+}
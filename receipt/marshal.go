@@ -0,0 +1,117 @@
+package receipt
+
+import (
+	"encoding/json"
+
+	"github.com/codaea/simpleprint/imaging"
+)
+
+func (Line) itemType() string       { return "line" }
+func (Text) itemType() string       { return "text" }
+func (Columns) itemType() string    { return "columns" }
+func (Feed) itemType() string       { return "feed" }
+func (Cut) itemType() string        { return "cut" }
+func (Drawer) itemType() string     { return "drawer" }
+func (Beep) itemType() string       { return "beep" }
+func (Barcode) itemType() string    { return "barcode" }
+func (QRCode) itemType() string     { return "qr" }
+func (PDF417) itemType() string     { return "pdf417" }
+func (DataMatrix) itemType() string { return "datamatrix" }
+func (Aztec) itemType() string      { return "aztec" }
+func (Image) itemType() string      { return "image" }
+func (NVImage) itemType() string    { return "nv_image" }
+
+// The MarshalJSON methods fill in the type field, so items can be written without it.
+// alias drops the methods so they don't recurse.
+
+func (l Line) MarshalJSON() ([]byte, error) {
+	type alias Line
+	l.Type = l.itemType()
+	return json.Marshal(alias(l))
+}
+
+func (t Text) MarshalJSON() ([]byte, error) {
+	type alias Text
+	t.Type = t.itemType()
+	return json.Marshal(alias(t))
+}
+
+func (c Columns) MarshalJSON() ([]byte, error) {
+	type alias Columns
+	c.Type = c.itemType()
+	return json.Marshal(alias(c))
+}
+
+func (f Feed) MarshalJSON() ([]byte, error) {
+	type alias Feed
+	f.Type = f.itemType()
+	return json.Marshal(alias(f))
+}
+
+func (c Cut) MarshalJSON() ([]byte, error) {
+	type alias Cut
+	c.Type = c.itemType()
+	return json.Marshal(alias(c))
+}
+
+func (d Drawer) MarshalJSON() ([]byte, error) {
+	type alias Drawer
+	d.Type = d.itemType()
+	return json.Marshal(alias(d))
+}
+
+func (b Beep) MarshalJSON() ([]byte, error) {
+	type alias Beep
+	b.Type = b.itemType()
+	return json.Marshal(alias(b))
+}
+
+func (b Barcode) MarshalJSON() ([]byte, error) {
+	type alias Barcode
+	b.Type = b.itemType()
+	return json.Marshal(alias(b))
+}
+
+func (q QRCode) MarshalJSON() ([]byte, error) {
+	type alias QRCode
+	q.Type = q.itemType()
+	return json.Marshal(alias(q))
+}
+
+func (c PDF417) MarshalJSON() ([]byte, error) {
+	type alias PDF417
+	c.Type = c.itemType()
+	return json.Marshal(alias(c))
+}
+
+func (c DataMatrix) MarshalJSON() ([]byte, error) {
+	type alias DataMatrix
+	c.Type = c.itemType()
+	return json.Marshal(alias(c))
+}
+
+func (c Aztec) MarshalJSON() ([]byte, error) {
+	type alias Aztec
+	c.Type = c.itemType()
+	return json.Marshal(alias(c))
+}
+
+func (i Image) MarshalJSON() ([]byte, error) {
+	type alias Image
+	i.Type = i.itemType()
+	// an image built in Go is sent as data
+	if i.Data == "" && i.Asset == "" && i.Img != nil {
+		data, err := imaging.EncodeDataURI(i.Img)
+		if err != nil {
+			return nil, err
+		}
+		i.Data = data
+	}
+	return json.Marshal(alias(i))
+}
+
+func (n NVImage) MarshalJSON() ([]byte, error) {
+	type alias NVImage
+	n.Type = n.itemType()
+	return json.Marshal(alias(n))
+}
@@ -0,0 +1,111 @@
+package receipt
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+)
+
+// QRErrorCorrection is the QR error correction level, H recovers the most damage
+type QRErrorCorrection string
+
+const (
+	QRLevelL QRErrorCorrection = "L"
+	QRLevelM QRErrorCorrection = "M"
+	QRLevelQ QRErrorCorrection = "Q"
+	QRLevelH QRErrorCorrection = "H"
+)
+
+func (l *QRErrorCorrection) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	switch s {
+	case "L", "M", "Q", "H":
+		*l = QRErrorCorrection(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid error_correction: %s. Must be L, M, Q, or H", s)
+	}
+}
+
+func (l QRErrorCorrection) toQRLevel() qr.ErrorCorrectionLevel {
+	switch l {
+	case QRLevelM:
+		return qr.M
+	case QRLevelQ:
+		return qr.Q
+	case QRLevelH:
+		return qr.H
+	default:
+		return qr.L
+	}
+}
+
+// QRCode is printed with GS ( k, or drawn as an image when Render is raster.
+// Only raster codes can have a logo, Logo is the name of an asset and LogoImg the decoded logo.
+type QRCode struct {
+	Type            string            `json:"type"`
+	Code            string            `json:"code" schema:"minLength=1"`
+	Size            int               `json:"size,omitempty" schema:"minimum=1,maximum=16"`
+	ErrorCorrection QRErrorCorrection `json:"error_correction,omitempty"`
+	Model           int               `json:"model,omitempty" schema:"enum=1|2"`
+	Alignment       AlignmentType     `json:"alignment,omitempty"`
+	Render          RenderMode        `json:"render,omitempty"`
+	Logo            string            `json:"logo,omitempty"`
+	LogoImg         image.Image       `json:"-"`
+}
+
+func (q *QRCode) UnmarshalJSON(data []byte) error {
+	type alias QRCode
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if err := checkSymbol(aux.Code, &aux.Size, 3, 1, 16, &aux.Alignment, &aux.Render); err != nil {
+		return err
+	}
+	switch aux.Model {
+	case 0:
+		aux.Model = 2
+	case 1, 2:
+	default:
+		return fieldErrorf("model", "invalid model: %d. Must be 1 or 2", aux.Model)
+	}
+
+	if aux.Logo != "" {
+		if aux.Render != RenderRaster {
+			return fieldErrorf("logo", "a logo can only be added with render raster")
+		}
+		// the logo covers part of the code, only level H has enough redundancy for that
+		if aux.ErrorCorrection != "" && aux.ErrorCorrection != QRLevelH {
+			return fieldErrorf("error_correction", "a logo needs error_correction H")
+		}
+		aux.ErrorCorrection = QRLevelH
+		logo, err := loadAsset(aux.Logo)
+		if err != nil {
+			return &fieldError{field: "logo", err: err}
+		}
+		aux.LogoImg = logo
+	}
+	if aux.ErrorCorrection == "" {
+		aux.ErrorCorrection = QRLevelL
+	}
+	if aux.Render == RenderRaster && aux.Model == 1 {
+		return fieldErrorf("model", "model 1 can only be printed natively")
+	}
+
+	*q = QRCode(aux)
+	return nil
+}
+
+// Encode draws the code in Go, for printing it as a raster image
+func (q QRCode) Encode() (barcode.Barcode, error) {
+	return qr.Encode(q.Code, q.ErrorCorrection.toQRLevel(), qr.Auto)
+}
@@ -0,0 +1,38 @@
+package receipt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQRCode_Defaults(t *testing.T) {
+	var q QRCode
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "qr", "code": "abc"}`), &q))
+	assert.Equal(t, 3, q.Size)
+	assert.Equal(t, 2, q.Model)
+	assert.Equal(t, QRLevelL, q.ErrorCorrection)
+	assert.Equal(t, AlignCenter, q.Alignment)
+	assert.Equal(t, RenderNative, q.Render)
+
+	// GS ( k takes QR module size 1, unlike the other symbols
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "qr", "code": "abc", "size": 1}`), &q))
+	assert.Equal(t, 1, q.Size)
+}
+
+func TestQRCode_Invalid(t *testing.T) {
+	tests := map[string]string{
+		`"error_correction": "X"`:        "invalid error_correction",
+		`"model": 3`:                     "invalid model",
+		`"size": 17`:                     "invalid size",
+		`"model": 1, "render": "raster"`: "model 1 can only be printed natively",
+		`"logo": "logo"`:                 "only be added with render raster",
+		`"logo": "logo", "render": "raster", "error_correction": "M"`: "needs error_correction H",
+	}
+	for fields, wantErr := range tests {
+		var q QRCode
+		err := json.Unmarshal([]byte(`{"code": "abc", `+fields+`}`), &q)
+		assert.ErrorContains(t, err, wantErr, fields)
+	}
+}
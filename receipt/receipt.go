@@ -0,0 +1,208 @@
+// Package receipt has the types of a SimplePrint print request. Decoding a Request checks every
+// field and fills in the defaults, so a decoded request is ready to be rendered.
+// Every item sets its own type field when it is marshaled, so receipts can be built in Go
+// with the Builder and sent to a server.
+package receipt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PaperWidth is the printable width in dots receipts are laid out for, 576 for a standard 80mm printer
+var PaperWidth = 576
+
+// LoadAsset returns the stored image called name, for image items and QR code logos that refer to an asset.
+// While it is nil there are no assets, and those items fail to decode.
+var LoadAsset func(name string) (image.Image, error)
+
+// CheckNVGraphic returns an error if no graphic is stored in the printer under key.
+// While it is nil every nv_image key that is valid is accepted.
+var CheckNVGraphic func(key string) error
+
+var errNoAssets = errors.New("no assets are available")
+
+func loadAsset(name string) (image.Image, error) {
+	if LoadAsset == nil {
+		return nil, errNoAssets
+	}
+	return LoadAsset(name)
+}
+
+// Request is the body of POST /print
+type Request struct {
+	Receipt []Item `json:"receipt"`
+	// Cut is how the paper is cut after the receipt, full if empty
+	Cut CutMode `json:"cut,omitempty"`
+	// FeedBeforeCut is the lines fed before every cut, the printer's default if nil
+	FeedBeforeCut *int `json:"feed_before_cut,omitempty" schema:"minimum=0,maximum=255"`
+	// Copies is how many times the receipt is printed, each copy is cut separately
+	Copies int `json:"copies,omitempty" schema:"minimum=1,maximum=20"`
+	// Variables replace {{name}} in the text of lines, text and columns items,
+	// CopyVariables[n] overrides them for copy n
+	Variables     map[string]string   `json:"variables,omitempty"`
+	CopyVariables []map[string]string `json:"copy_variables,omitempty"`
+}
+
+// Item is one of the receipt item types of this package
+type Item interface {
+	itemType() string
+}
+
+// ItemTypes are the Go types of the receipt items by their type field
+var ItemTypes = map[string]reflect.Type{
+	"line":       reflect.TypeOf(Line{}),
+	"text":       reflect.TypeOf(Text{}),
+	"columns":    reflect.TypeOf(Columns{}),
+	"barcode":    reflect.TypeOf(Barcode{}),
+	"qr":         reflect.TypeOf(QRCode{}),
+	"pdf417":     reflect.TypeOf(PDF417{}),
+	"datamatrix": reflect.TypeOf(DataMatrix{}),
+	"aztec":      reflect.TypeOf(Aztec{}),
+	"image":      reflect.TypeOf(Image{}),
+	"nv_image":   reflect.TypeOf(NVImage{}),
+	"cut":        reflect.TypeOf(Cut{}),
+	"drawer":     reflect.TypeOf(Drawer{}),
+	"beep":       reflect.TypeOf(Beep{}),
+	"feed":       reflect.TypeOf(Feed{}),
+}
+
+// MaxCopies is the most copies a request can print
+const MaxCopies = 20
+
+// UnmarshalJSON checks every field and receipt item, and returns all the problems found
+// together as a *ValidationError
+func (r *Request) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Receipt       []json.RawMessage   `json:"receipt"`
+		Cut           CutMode             `json:"cut"`
+		FeedBeforeCut *int                `json:"feed_before_cut"`
+		Copies        int                 `json:"copies"`
+		Variables     map[string]string   `json:"variables"`
+		CopyVariables []map[string]string `json:"copy_variables"`
+	}
+
+	errs := &ValidationError{}
+	if !decodeFields(errs, "", data, &raw) && raw.Receipt == nil {
+		return errs
+	}
+
+	r.Cut = raw.Cut
+	if r.Cut == "" {
+		r.Cut = CutFull
+	}
+	if raw.FeedBeforeCut != nil && (*raw.FeedBeforeCut < 0 || *raw.FeedBeforeCut > 255) {
+		errs.add("/feed_before_cut", fmt.Errorf("invalid feed_before_cut: %d. Must be 0-255", *raw.FeedBeforeCut))
+	}
+	r.FeedBeforeCut = raw.FeedBeforeCut
+
+	r.Copies = raw.Copies
+	if r.Copies == 0 {
+		r.Copies = max(1, len(raw.CopyVariables))
+	}
+	if r.Copies < 1 || r.Copies > MaxCopies {
+		errs.add("/copies", fmt.Errorf("invalid copies: %d. Must be 1-%d", r.Copies, MaxCopies))
+	} else if len(raw.CopyVariables) > r.Copies {
+		errs.add("/copy_variables", fmt.Errorf("copy_variables has %d entries but only %d copies are printed", len(raw.CopyVariables), r.Copies))
+	}
+	r.Variables = raw.Variables
+	r.CopyVariables = raw.CopyVariables
+
+	r.Receipt = make([]Item, len(raw.Receipt))
+	for i, itemData := range raw.Receipt {
+		r.Receipt[i] = decodeItem(errs, "/receipt/"+strconv.Itoa(i), itemData)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// decodeItem decodes one receipt item by its type, adding what is wrong with it to errs
+func decodeItem(errs *ValidationError, path string, data []byte) Item {
+	var typeExtractor struct {
+		Type *string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &typeExtractor); err != nil {
+		errs.add(path, typeError(err, reflect.TypeOf(map[string]any{})))
+		return nil
+	}
+	if typeExtractor.Type == nil {
+		errs.add(path+"/type", errors.New("is required"))
+		return nil
+	}
+	t, ok := ItemTypes[*typeExtractor.Type]
+	if !ok {
+		errs.add(path+"/type", fmt.Errorf("unknown receipt item type: %s", *typeExtractor.Type))
+		return nil
+	}
+
+	// the fields are checked one by one first, so every bad field is reported,
+	// then the item is decoded as a whole for its defaults and the checks across fields
+	item := reflect.New(t)
+	if !decodeFields(errs, path, data, item.Interface()) {
+		return nil
+	}
+	if err := json.Unmarshal(data, item.Interface()); err != nil {
+		errs.add(path, err)
+		return nil
+	}
+	return item.Elem().Interface().(Item)
+}
+
+// VariablesFor returns the variables of copy n (from 0), with its copy_variables on top of the shared ones.
+// copy_number and copies are always set, unless the request sets them itself.
+func (r Request) VariablesFor(n int) map[string]string {
+	vars := map[string]string{
+		"copy_number": strconv.Itoa(n + 1),
+		"copies":      strconv.Itoa(max(1, r.Copies)),
+	}
+	for name, value := range r.Variables {
+		vars[name] = value
+	}
+	if n < len(r.CopyVariables) {
+		for name, value := range r.CopyVariables[n] {
+			vars[name] = value
+		}
+	}
+	return vars
+}
+
+// substitute replaces {{name}} with the value of each variable, unknown names are left as they are
+func substitute(s string, vars map[string]string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	pairs := make([]string, 0, len(vars)*2)
+	for name, value := range vars {
+		pairs = append(pairs, "{{"+name+"}}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// WithVariables returns item with the variables substituted in its text
+func WithVariables(item Item, vars map[string]string) Item {
+	switch v := item.(type) {
+	case Line:
+		v.Content = substitute(v.Content, vars)
+		return v
+	case Text:
+		v.Content = substitute(v.Content, vars)
+		return v
+	case Columns:
+		cols := make([]Column, len(v.Columns))
+		for i, col := range v.Columns {
+			col.Content = substitute(col.Content, vars)
+			cols[i] = col
+		}
+		v.Columns = cols
+		return v
+	}
+	return item
+}
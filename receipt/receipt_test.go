@@ -0,0 +1,37 @@
+package receipt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequest_InvalidCopies(t *testing.T) {
+	var req Request
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"copies": 100, "receipt": []}`), &req), "invalid copies")
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"copies": 1, "copy_variables": [{}, {}], "receipt": []}`), &req), "only 1 copies")
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"copies": 3, "receipt": []}`), &req))
+	assert.Equal(t, 3, req.Copies)
+}
+
+func TestDefaultStyle(t *testing.T) {
+	DefaultStyle = TextStyle{Font: FontB, FontSize: 2, Alignment: AlignCenter}
+	DefaultCodePage = CodePagePC850
+	defer func() {
+		DefaultStyle = TextStyle{}
+		DefaultCodePage = ""
+	}()
+
+	var req Request
+	assert.NoError(t, json.Unmarshal([]byte(`{"receipt": [
+		{"type": "line", "content": "default"},
+		{"type": "text", "content": "set", "font": "A", "font_size": 1, "alignment": "left", "code_page": "PC437"},
+		{"type": "columns", "columns": [{"content": "a"}]}
+	]}`), &req))
+	assert.Equal(t, Line{Type: "line", Content: "default", Font: FontB, FontSize: 2, Alignment: AlignCenter, CodePage: CodePagePC850}, req.Receipt[0])
+	assert.Equal(t, Text{Type: "text", Content: "set", Font: FontA, FontSize: 1, Alignment: AlignLeft, CodePage: CodePagePC437}, req.Receipt[1])
+	assert.Equal(t, FontB, req.Receipt[2].(Columns).Font)
+	assert.Equal(t, 2, req.Receipt[2].(Columns).FontSize)
+}
@@ -0,0 +1,186 @@
+package receipt
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/aztec"
+	"github.com/boombuler/barcode/datamatrix"
+	"github.com/boombuler/barcode/pdf417"
+)
+
+// RenderMode is how a 2D code is printed. Native uses the printer's GS ( k command,
+// raster draws the code in Go and prints it as an image, for printers without GS ( k support.
+type RenderMode string
+
+const (
+	RenderNative RenderMode = "native"
+	RenderRaster RenderMode = "raster"
+)
+
+func (r *RenderMode) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	switch s {
+	case "native", "raster":
+		*r = RenderMode(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid render: %s. Must be native or raster", s)
+	}
+}
+
+// checkSymbol validates the fields every 2D code has and fills in the defaults.
+// nativeMin is the smallest size GS ( k takes for the symbol, a raster code can be 1.
+func checkSymbol(code string, size *int, defaultSize, nativeMin, maxSize int, alignment *AlignmentType, render *RenderMode) error {
+	if code == "" {
+		return fieldErrorf("code", "code is empty")
+	}
+	if *size == 0 {
+		*size = defaultSize
+	}
+	if *size < 1 || *size > maxSize {
+		return fieldErrorf("size", "invalid size: %d. Must be 1-%d", *size, maxSize)
+	}
+	if *alignment == "" {
+		*alignment = AlignCenter
+	}
+	if *render == "" {
+		*render = RenderNative
+	}
+	if *render == RenderNative && *size < nativeMin {
+		return fieldErrorf("size", "invalid size: %d. Must be %d-%d when printed natively", *size, nativeMin, maxSize)
+	}
+	return nil
+}
+
+// PDF417 is printed with GS ( k, or drawn as an image when Render is raster.
+// Size is the module width in dots, ErrorCorrection is level 1 if nil.
+type PDF417 struct {
+	Type            string        `json:"type"`
+	Code            string        `json:"code" schema:"minLength=1"`
+	Size            int           `json:"size,omitempty" schema:"minimum=1,maximum=8"`
+	ErrorCorrection *int          `json:"error_correction,omitempty" schema:"minimum=0,maximum=8"`
+	Columns         int           `json:"columns,omitempty" schema:"minimum=0,maximum=30"`
+	Rows            int           `json:"rows,omitempty" schema:"minimum=0,maximum=90"`
+	Alignment       AlignmentType `json:"alignment,omitempty"`
+	Render          RenderMode    `json:"render,omitempty"`
+}
+
+func (c *PDF417) UnmarshalJSON(data []byte) error {
+	type alias PDF417
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if err := checkSymbol(aux.Code, &aux.Size, 3, 2, 8, &aux.Alignment, &aux.Render); err != nil {
+		return err
+	}
+	if aux.ErrorCorrection == nil {
+		level := 1
+		aux.ErrorCorrection = &level
+	}
+	if *aux.ErrorCorrection < 0 || *aux.ErrorCorrection > 8 {
+		return fieldErrorf("error_correction", "invalid error_correction: %d. Must be 0-8", *aux.ErrorCorrection)
+	}
+	if aux.Columns < 0 || aux.Columns > 30 {
+		return fieldErrorf("columns", "invalid columns: %d. Must be 1-30, or 0 for automatic", aux.Columns)
+	}
+	if aux.Rows != 0 && (aux.Rows < 3 || aux.Rows > 90) {
+		return fieldErrorf("rows", "invalid rows: %d. Must be 3-90, or 0 for automatic", aux.Rows)
+	}
+
+	*c = PDF417(aux)
+	return nil
+}
+
+// Level is the error correction level, 1 if ErrorCorrection is nil
+func (c PDF417) Level() int {
+	if c.ErrorCorrection == nil {
+		return 1
+	}
+	return *c.ErrorCorrection
+}
+
+// Encode draws the code in Go, for printing it as a raster image
+func (c PDF417) Encode() (barcode.Barcode, error) {
+	return pdf417.Encode(c.Code, byte(c.Level()))
+}
+
+// DataMatrix is printed with GS ( k, or drawn as an image when Render is raster.
+// Size is the module size in dots.
+type DataMatrix struct {
+	Type      string        `json:"type"`
+	Code      string        `json:"code" schema:"minLength=1"`
+	Size      int           `json:"size,omitempty" schema:"minimum=1,maximum=16"`
+	Alignment AlignmentType `json:"alignment,omitempty"`
+	Render    RenderMode    `json:"render,omitempty"`
+}
+
+func (c *DataMatrix) UnmarshalJSON(data []byte) error {
+	type alias DataMatrix
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if err := checkSymbol(aux.Code, &aux.Size, 4, 2, 16, &aux.Alignment, &aux.Render); err != nil {
+		return err
+	}
+
+	*c = DataMatrix(aux)
+	return nil
+}
+
+func (c DataMatrix) Encode() (barcode.Barcode, error) {
+	return datamatrix.Encode(c.Code)
+}
+
+// Aztec is printed with GS ( k, or drawn as an image when Render is raster.
+// Size is the module size in dots and ErrorCorrection a percentage.
+type Aztec struct {
+	Type            string        `json:"type"`
+	Code            string        `json:"code" schema:"minLength=1"`
+	Size            int           `json:"size,omitempty" schema:"minimum=1,maximum=16"`
+	ErrorCorrection int           `json:"error_correction,omitempty" schema:"minimum=5,maximum=95"`
+	Layers          int           `json:"layers,omitempty" schema:"minimum=0,maximum=32"`
+	Alignment       AlignmentType `json:"alignment,omitempty"`
+	Render          RenderMode    `json:"render,omitempty"`
+}
+
+func (c *Aztec) UnmarshalJSON(data []byte) error {
+	type alias Aztec
+	var aux alias
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if err := checkSymbol(aux.Code, &aux.Size, 4, 2, 16, &aux.Alignment, &aux.Render); err != nil {
+		return err
+	}
+	if aux.ErrorCorrection == 0 {
+		aux.ErrorCorrection = aztec.DEFAULT_EC_PERCENT
+	}
+	if aux.ErrorCorrection < 5 || aux.ErrorCorrection > 95 {
+		return fieldErrorf("error_correction", "invalid error_correction: %d. Must be 5-95 percent", aux.ErrorCorrection)
+	}
+	if aux.Layers < 0 || aux.Layers > 32 {
+		return fieldErrorf("layers", "invalid layers: %d. Must be 1-32, or 0 for automatic", aux.Layers)
+	}
+	// GS ( k prints in full range mode, which has at least 4 layers
+	if aux.Render == RenderNative && aux.Layers > 0 && aux.Layers < 4 {
+		return fieldErrorf("layers", "invalid layers: %d. Must be 4-32, or 0 for automatic, when printed natively", aux.Layers)
+	}
+
+	*c = Aztec(aux)
+	return nil
+}
+
+func (c Aztec) Encode() (barcode.Barcode, error) {
+	return aztec.Encode([]byte(c.Code), c.ErrorCorrection, c.Layers)
+}
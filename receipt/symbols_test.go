@@ -0,0 +1,25 @@
+package receipt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSymbols_Invalid(t *testing.T) {
+	var pdf PDF417
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"code": "x", "error_correction": 9}`), &pdf), "invalid error_correction")
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"code": "x", "rows": 2}`), &pdf), "invalid rows")
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"code": "x", "size": 1}`), &pdf), "when printed natively")
+	assert.NoError(t, json.Unmarshal([]byte(`{"code": "x", "size": 1, "render": "raster"}`), &pdf))
+
+	var dm DataMatrix
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"code": ""}`), &dm), "code is empty")
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"code": "x", "render": "bitmap"}`), &dm), "invalid render")
+
+	var az Aztec
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"code": "x", "error_correction": 99}`), &az), "invalid error_correction")
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"code": "x", "layers": 2}`), &az), "Must be 4-32")
+	assert.NoError(t, json.Unmarshal([]byte(`{"code": "x", "layers": 2, "render": "raster"}`), &az))
+}
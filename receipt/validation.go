@@ -0,0 +1,185 @@
+package receipt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/codaea/simpleprint/internal/jsonfield"
+)
+
+// FieldError is a problem with one field of a request, Path is a JSON pointer to it like /receipt/3/font_size
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	// Line is where the receipt item starts in a YAML or TOML body
+	Line int `json:"line,omitempty"`
+}
+
+// ValidationResult is the response of a dry run. Errors stop the request from printing,
+// warnings are for things that print differently than they probably should.
+type ValidationResult struct {
+	Valid    bool         `json:"valid"`
+	Errors   []FieldError `json:"errors"`
+	Warnings []FieldError `json:"warnings"`
+}
+
+// ValidationError is every problem found in a request, so they can all be fixed at once
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Message
+		if fe.Path != "" {
+			msgs[i] = fe.Path + ": " + msgs[i]
+		}
+		if fe.Line > 0 {
+			msgs[i] = fmt.Sprintf("line %d: %s", fe.Line, msgs[i])
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// add records err at path, or at the field of path it is about
+func (e *ValidationError) add(path string, err error) {
+	var fe *fieldError
+	if errors.As(err, &fe) {
+		path += "/" + fe.field
+	}
+	e.Errors = append(e.Errors, FieldError{Path: path, Message: err.Error()})
+}
+
+// fieldError is an error caused by one field of an item, field is its path inside the item
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+func fieldErrorf(field, format string, args ...any) error {
+	return &fieldError{field: field, err: fmt.Errorf(format, args...)}
+}
+
+// decodeFields decodes every field of the JSON object data on its own into the struct v points to.
+// That finds unknown fields and all invalid values at once, where decoding the whole object
+// stops at the first. Arrays of structs are decoded the same way, element by element.
+// It returns false if anything was wrong.
+func decodeFields(errs *ValidationError, path string, data []byte, v any) bool {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil || values == nil {
+		errs.add(path, errors.New("must be an object"))
+		return false
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	target := reflect.ValueOf(v).Elem()
+	fields := jsonfield.Fields(target.Type())
+	ok := true
+	for _, name := range names {
+		fieldPath := path + "/" + escapePointer(name)
+		field, found := fields[name]
+		if !found {
+			errs.add(fieldPath, errors.New("unknown field"))
+			ok = false
+			continue
+		}
+
+		value := target.FieldByIndex(field.Index)
+		if isStructSlice(field.Type) {
+			var elems []json.RawMessage
+			if err := json.Unmarshal(values[name], &elems); err != nil {
+				errs.add(fieldPath, typeError(err, field.Type))
+				ok = false
+				continue
+			}
+			value.Set(reflect.MakeSlice(field.Type, len(elems), len(elems)))
+			for i, elem := range elems {
+				if !decodeFields(errs, fieldPath+"/"+strconv.Itoa(i), elem, value.Index(i).Addr().Interface()) {
+					ok = false
+				}
+			}
+			continue
+		}
+		if err := json.Unmarshal(values[name], value.Addr().Interface()); err != nil {
+			errs.add(fieldPath, typeError(err, field.Type))
+			ok = false
+		}
+	}
+	return ok
+}
+
+// DecodeItem decodes the JSON object data into the item v points to the way an item of a request is decoded,
+// so unknown fields and every invalid value are reported in a ValidationError
+func DecodeItem(data []byte, v any) error {
+	errs := &ValidationError{}
+	if !decodeFields(errs, "", data, v) {
+		return errs
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		errs.add("", err)
+		return errs
+	}
+	return nil
+}
+
+// isStructSlice reports if t is a slice of structs that decode field by field
+func isStructSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	_, custom := reflect.New(t.Elem()).Interface().(json.Unmarshaler)
+	return !custom
+}
+
+// typeError rewords a JSON type mismatch in terms of the JSON value t expects
+func typeError(err error, t reflect.Type) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("must be %s, not %s", jsonKind(t), typeErr.Value)
+	}
+	return err
+}
+
+// jsonKind describes the JSON value a Go type is decoded from
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonKind(t.Elem())
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// escapePointer escapes a JSON pointer reference token
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
@@ -0,0 +1,93 @@
+package receipt
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/codaea/simpleprint/imaging"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPNG returns a width x height gray gradient as a PNG data URI
+func testPNG(width, height int) string {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8(x * 255 / width)})
+		}
+	}
+	data, _ := imaging.EncodeDataURI(img)
+	return data
+}
+
+// validationPaths unmarshals body and returns the path of every field error
+func validationPaths(t *testing.T, body string) []string {
+	var req Request
+	err := json.Unmarshal([]byte(body), &req)
+	var verr *ValidationError
+	if !assert.True(t, errors.As(err, &verr), "expected a ValidationError, got %v", err) {
+		return nil
+	}
+	paths := make([]string, len(verr.Errors))
+	for i, fe := range verr.Errors {
+		paths[i] = fe.Path
+	}
+	return paths
+}
+
+func TestValidation_UnknownFields(t *testing.T) {
+	assert.Equal(t, []string{"/receipt/0/dither-mode", "/receipt/1/font-size"}, validationPaths(t, `{"receipt": [
+		{"type": "image", "data": "`+testPNG(8, 8)+`", "dither-mode": "none"},
+		{"type": "line", "content": "hi", "font-size": 2}
+	]}`))
+	assert.Equal(t, []string{"/cuts"}, validationPaths(t, `{"cuts": "none", "receipt": []}`))
+	assert.Equal(t, []string{"/receipt/0/columns/1/align"}, validationPaths(t, `{"receipt": [
+		{"type": "columns", "columns": [{"content": "a"}, {"content": "b", "align": "right"}]}
+	]}`))
+	assert.Equal(t, []string{"/receipt/0/a~1b"}, validationPaths(t, `{"receipt": [{"type": "feed", "a/b": 1}]}`))
+}
+
+func TestValidation_Ranges(t *testing.T) {
+	assert.Equal(t, []string{"/receipt/0/font_size"}, validationPaths(t, `{"receipt": [{"type": "line", "content": "x", "font_size": 300}]}`))
+	assert.Equal(t, []string{"/receipt/0/font_size"}, validationPaths(t, `{"receipt": [{"type": "text", "content": "x", "font_size": 9}]}`))
+	assert.Equal(t, []string{"/receipt/0/size"}, validationPaths(t, `{"receipt": [{"type": "qr", "code": "x", "size": 17}]}`))
+	assert.Equal(t, []string{"/receipt/0/lines"}, validationPaths(t, `{"receipt": [{"type": "feed", "lines": 256}]}`))
+	assert.Equal(t, []string{"/receipt/0/font"}, validationPaths(t, `{"receipt": [{"type": "line", "content": "x", "font": "D"}]}`))
+	assert.Equal(t, []string{"/receipt/0/dither_mode"}, validationPaths(t, `{"receipt": [{"type": "image", "data": "`+testPNG(8, 8)+`", "dither_mode": "atkinson"}]}`))
+
+	var req Request
+	assert.NoError(t, json.Unmarshal([]byte(`{"receipt": [{"type": "line", "content": "x"}]}`), &req))
+	assert.Equal(t, 1, req.Receipt[0].(Line).FontSize)
+}
+
+func TestValidation_AllErrorsAtOnce(t *testing.T) {
+	paths := validationPaths(t, `{"copies": 50, "receipt": [
+		{"type": "line", "content": "ok"},
+		{"type": "line", "content": 5, "font": "Z"},
+		{"content": "no type"},
+		{"type": "sparkles"},
+		{"type": "barcode", "code": "123", "barcode_type": "EAN13"},
+		"not an item"
+	]}`)
+	assert.Equal(t, []string{
+		"/copies",
+		"/receipt/1/content",
+		"/receipt/1/font",
+		"/receipt/2/type",
+		"/receipt/3/type",
+		"/receipt/4/code",
+		"/receipt/5",
+	}, paths)
+}
+
+func TestValidation_Messages(t *testing.T) {
+	var req Request
+	err := json.Unmarshal([]byte(`{"receipt": [{"type": "line", "content": 5, "font_size": 0}]}`), &req)
+	assert.EqualError(t, err, "/receipt/0/content: must be a string, not number")
+
+	err = json.Unmarshal([]byte(`[]`), &req)
+	assert.EqualError(t, err, "must be an object")
+}
@@ -0,0 +1,144 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/codaea/simpleprint/receipt"
+)
+
+// barcodeFunctionB is the GS k function B code of every barcode type
+var barcodeFunctionB = map[receipt.BarcodeType]byte{
+	receipt.BarcodeUPCA:                65,
+	receipt.BarcodeUPCE:                66,
+	receipt.BarcodeEAN13:               67,
+	receipt.BarcodeEAN8:                68,
+	receipt.BarcodeCODE39:              69,
+	receipt.BarcodeITF:                 70,
+	receipt.BarcodeCODABAR:             71,
+	receipt.BarcodeCODE93:              72,
+	receipt.BarcodeCODE128:             73,
+	receipt.BarcodeGS1128:              74,
+	receipt.BarcodeGS1DataBar:          75,
+	receipt.BarcodeGS1DataBarTruncated: 76,
+	receipt.BarcodeGS1DataBarLimited:   77,
+	receipt.BarcodeGS1DataBarExpanded:  78,
+}
+
+// hriPosition returns n of GS H
+func hriPosition(h receipt.HRIPosition) byte {
+	switch h {
+	case receipt.HRINone:
+		return 0
+	case receipt.HRIAbove:
+		return 1
+	case receipt.HRIBoth:
+		return 3
+	default:
+		return 2 // below
+	}
+}
+
+// Barcode sends the barcode with GS k function B, which has every barcode type.
+// go-escpos only has the older function A types.
+func (p *ESCPOS) Barcode(b receipt.Barcode) error {
+	p.Align(b.Alignment)
+	m, ok := barcodeFunctionB[b.BarcodeType]
+	if !ok {
+		return fmt.Errorf("invalid barcode type: %s", b.BarcodeType)
+	}
+
+	// GS w module width, GS h height, GS H HRI position and GS f HRI font
+	err := p.Raw(
+		0x1D, 'w', byte(b.ModuleWidth),
+		0x1D, 'h', byte(b.Height),
+		0x1D, 'H', hriPosition(b.HRIPosition),
+		0x1D, 'f', byte(escposFont(b.HRIFont)),
+	)
+	if err != nil {
+		return err
+	}
+	cmd := append([]byte{0x1D, 'k', m, byte(len(b.Code))}, b.Code...)
+	return p.Raw(cmd...)
+}
+
+// GS ( k symbol types
+const (
+	symbolPDF417     = 48
+	symbolQR         = 49
+	symbolAztec      = 53
+	symbolDataMatrix = 54
+)
+
+// gsK builds GS ( k function fn for symbol type cn
+func gsK(cn, fn byte, params ...byte) []byte {
+	n := len(params) + 2
+	return append([]byte{0x1D, '(', 'k', byte(n), byte(n >> 8), cn, fn}, params...)
+}
+
+// printSymbol sends the setup commands, stores the code in the symbol storage area and prints it
+func (p *ESCPOS) printSymbol(cn byte, code string, setup ...[]byte) error {
+	for _, cmd := range setup {
+		if err := p.Raw(cmd...); err != nil {
+			return err
+		}
+	}
+	if err := p.Raw(gsK(cn, 80, append([]byte{48}, code...)...)...); err != nil {
+		return err
+	}
+	return p.Raw(gsK(cn, 81, 48)...)
+}
+
+// qrLevel returns n of GS ( k function 69 for QR codes
+func qrLevel(l receipt.QRErrorCorrection) byte {
+	switch l {
+	case receipt.QRLevelM:
+		return 49
+	case receipt.QRLevelQ:
+		return 50
+	case receipt.QRLevelH:
+		return 51
+	default:
+		return 48 // L
+	}
+}
+
+// QR prints a QR code with the printer's QR command, or as an image if it doesn't have one
+func (p *ESCPOS) QR(q receipt.QRCode) error {
+	if !p.Features.QR {
+		return printQRRaster(p, q)
+	}
+	p.Align(q.Alignment)
+	return p.printSymbol(symbolQR, q.Code,
+		gsK(symbolQR, 65, byte(48+q.Model), 0),
+		gsK(symbolQR, 67, byte(q.Size)),
+		gsK(symbolQR, 69, qrLevel(q.ErrorCorrection)),
+	)
+}
+
+func (p *ESCPOS) PDF417(c receipt.PDF417) error {
+	p.Align(c.Alignment)
+	return p.printSymbol(symbolPDF417, c.Code,
+		gsK(symbolPDF417, 65, byte(c.Columns)),
+		gsK(symbolPDF417, 66, byte(c.Rows)),
+		gsK(symbolPDF417, 67, byte(c.Size)),
+		gsK(symbolPDF417, 68, 3), // row height, in multiples of the module width
+		gsK(symbolPDF417, 69, 48, byte(48+c.Level())),
+	)
+}
+
+func (p *ESCPOS) DataMatrix(c receipt.DataMatrix) error {
+	p.Align(c.Alignment)
+	return p.printSymbol(symbolDataMatrix, c.Code,
+		gsK(symbolDataMatrix, 65, 0, 0, 0), // square, automatic size
+		gsK(symbolDataMatrix, 67, byte(c.Size)),
+	)
+}
+
+func (p *ESCPOS) Aztec(c receipt.Aztec) error {
+	p.Align(c.Alignment)
+	return p.printSymbol(symbolAztec, c.Code,
+		gsK(symbolAztec, 48, 0, byte(c.Layers)), // full range mode
+		gsK(symbolAztec, 49, byte(c.Size)),
+		gsK(symbolAztec, 50, byte(c.ErrorCorrection)),
+	)
+}
@@ -0,0 +1,103 @@
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBarcodeFunctionB(t *testing.T) {
+	assert.Equal(t, byte(72), barcodeFunctionB[receipt.BarcodeCODE93])
+	assert.Len(t, barcodeFunctionB, len(receipt.BarcodeTypes))
+
+	p, _ := newBufferPrinter()
+	assert.ErrorContains(t, p.Barcode(receipt.Barcode{BarcodeType: "NOPE"}), "invalid barcode type")
+}
+
+func TestESCPOS_Barcode(t *testing.T) {
+	p, dev := newBufferPrinter()
+
+	var b receipt.Barcode
+	err := json.Unmarshal([]byte(`{"type": "barcode", "code": "No {1}", "barcode_type": "CODE128"}`), &b)
+	assert.NoError(t, err)
+	assert.Equal(t, receipt.AlignCenter, b.Alignment)
+
+	assert.NoError(t, p.Barcode(b))
+	assert.Equal(t, "\x1ba\x01\x1dw\x04\x1dh\x64\x1dH\x02\x1df\x00\x1dk\x49\x09{BNo {{1}", dev.String())
+}
+
+func TestESCPOS_BarcodeLayout(t *testing.T) {
+	p, dev := newBufferPrinter()
+
+	var b receipt.Barcode
+	err := json.Unmarshal([]byte(`{
+		"type": "barcode",
+		"code": "4006381333931",
+		"barcode_type": "EAN13",
+		"height": 50,
+		"module_width": 2,
+		"hri_position": "both",
+		"hri_font": "B",
+		"alignment": "left"
+	}`), &b)
+	assert.NoError(t, err)
+
+	assert.NoError(t, p.Barcode(b))
+	assert.Equal(t, "\x1ba\x00\x1dw\x02\x1dh\x32\x1dH\x03\x1df\x01\x1dk\x43\x0d4006381333931", dev.String())
+}
+
+func TestESCPOS_QR(t *testing.T) {
+	p, dev := newBufferPrinter()
+
+	var q receipt.QRCode
+	err := json.Unmarshal([]byte(`{"type": "qr", "code": "https://example.com", "size": 6, "error_correction": "Q", "model": 1, "alignment": "right"}`), &q)
+	assert.NoError(t, err)
+	assert.NoError(t, p.QR(q))
+
+	assert.Equal(t, "\x1ba\x02"+
+		"\x1d(k\x04\x001A1\x00"+
+		"\x1d(k\x03\x001C\x06"+
+		"\x1d(k\x03\x001E2"+
+		"\x1d(k\x16\x001P0https://example.com"+
+		"\x1d(k\x03\x001Q0", dev.String())
+}
+
+func TestESCPOS_Aztec(t *testing.T) {
+	p, dev := newBufferPrinter()
+
+	var c receipt.Aztec
+	err := json.Unmarshal([]byte(`{"type": "aztec", "code": "ABC123", "size": 5, "error_correction": 33, "layers": 6}`), &c)
+	assert.NoError(t, err)
+	assert.NoError(t, p.Aztec(c))
+
+	assert.Equal(t, "\x1ba\x01"+
+		"\x1d(k\x04\x005\x30\x00\x06"+ // full range mode, 6 layers
+		"\x1d(k\x03\x005\x31\x05"+ // module size 5
+		"\x1d(k\x03\x005\x32\x21"+ // 33% error correction
+		"\x1d(k\x09\x005P0ABC123"+
+		"\x1d(k\x03\x005Q0", dev.String())
+}
+
+func TestGsK(t *testing.T) {
+	assert.Equal(t, []byte{0x1D, '(', 'k', 3, 0, 48, 67, 3}, gsK(symbolPDF417, 67, 3))
+	assert.Equal(t, []byte{0x1D, '(', 'k', 6, 0, 54, 80, 48, 'a', 'b', 'c'}, gsK(symbolDataMatrix, 80, 48, 'a', 'b', 'c'))
+}
+
+func TestESCPOS_PDF417(t *testing.T) {
+	p, dev := newBufferPrinter()
+
+	var c receipt.PDF417
+	err := json.Unmarshal([]byte(`{"type": "pdf417", "code": "M1DOE/JOHN", "size": 2, "error_correction": 4, "columns": 5}`), &c)
+	assert.NoError(t, err)
+	assert.NoError(t, p.PDF417(c))
+
+	out := dev.String()
+	assert.Contains(t, out, "\x1d(k\x03\x000A\x05") // 5 columns
+	assert.Contains(t, out, "\x1d(k\x03\x000C\x02") // module width 2
+	assert.Contains(t, out, "\x1d(k\x04\x000E04")   // error correction level 4
+	assert.Contains(t, out, "\x1d(k\x0d\x000P0M1DOE/JOHN")
+	assert.True(t, strings.HasSuffix(out, "\x1d(k\x03\x000Q0"))
+}
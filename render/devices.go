@@ -0,0 +1,61 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Device is a USB printer the usblp driver found
+type Device struct {
+	// Path is the device file, like /dev/usb/lp0
+	Path         string
+	Manufacturer string
+	Product      string
+	Serial       string
+	// VendorID and ProductID are the 4 hex digit USB IDs
+	VendorID  string
+	ProductID string
+}
+
+// devDir and sysDir are where devices and their USB descriptors are looked up
+var (
+	devDir = "/dev/usb"
+	sysDir = "/sys/class/usbmisc"
+)
+
+// ListDevices returns the USB printers in the order of their device files.
+// Their descriptors are read from sysfs, they are empty where it isn't available.
+func ListDevices() ([]Device, error) {
+	entries, err := os.ReadDir(devDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []Device
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "lp") {
+			continue
+		}
+		d := Device{Path: filepath.Join(devDir, entry.Name())}
+		// device links to the printer interface, the USB device is its parent
+		if iface, err := filepath.EvalSymlinks(filepath.Join(sysDir, entry.Name(), "device")); err == nil {
+			usb := filepath.Dir(iface)
+			d.Manufacturer = readAttr(usb, "manufacturer")
+			d.Product = readAttr(usb, "product")
+			d.Serial = readAttr(usb, "serial")
+			d.VendorID = readAttr(usb, "idVendor")
+			d.ProductID = readAttr(usb, "idProduct")
+		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+func readAttr(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,35 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDevices(t *testing.T) {
+	dir := t.TempDir()
+	devDir, sysDir = filepath.Join(dir, "dev"), filepath.Join(dir, "sys")
+	defer func() { devDir, sysDir = "/dev/usb", "/sys/class/usbmisc" }()
+
+	for _, name := range []string{"lp1", "lp0", "hiddev0"} {
+		assert.NoError(t, os.MkdirAll(devDir, 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(devDir, name), nil, 0o644))
+	}
+	// lp0 has descriptors, its device links to the interface of the USB device like in sysfs
+	usb := filepath.Join(dir, "devices", "1-1")
+	assert.NoError(t, os.MkdirAll(filepath.Join(usb, "1-1:1.0"), 0o755))
+	for name, value := range map[string]string{"manufacturer": "EPSON\n", "product": "TM-T20II\n", "idVendor": "04b8\n", "idProduct": "0e15\n"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(usb, name), []byte(value), 0o644))
+	}
+	assert.NoError(t, os.MkdirAll(filepath.Join(sysDir, "lp0"), 0o755))
+	assert.NoError(t, os.Symlink(filepath.Join(usb, "1-1:1.0"), filepath.Join(sysDir, "lp0", "device")))
+
+	devices, err := ListDevices()
+	assert.NoError(t, err)
+	assert.Equal(t, []Device{
+		{Path: filepath.Join(devDir, "lp0"), Manufacturer: "EPSON", Product: "TM-T20II", VendorID: "04b8", ProductID: "0e15"},
+		{Path: filepath.Join(devDir, "lp1")},
+	}, devices)
+}
@@ -0,0 +1,96 @@
+package render
+
+import (
+	"image"
+	"sort"
+
+	"github.com/codaea/simpleprint/imaging"
+	"github.com/codaea/simpleprint/receipt"
+)
+
+// NVImage prints the graphic stored under its key with GS ( L
+func (p *ESCPOS) NVImage(n receipt.NVImage) error {
+	p.Align(n.Alignment)
+	var x, y byte = 1, 1
+	if n.DoubleWidth {
+		x = 2
+	}
+	if n.DoubleHeight {
+		y = 2
+	}
+	return p.Raw(0x1D, '(', 'L', 6, 0, 48, 69, n.Key[0], n.Key[1], x, y)
+}
+
+// NVBitImage prints NV bit image number with FS p, for printers without GS ( L.
+// The images defined with DefineNVBitImages are numbered from 1 in key order.
+func (p *ESCPOS) NVBitImage(number int, n receipt.NVImage) error {
+	p.Align(n.Alignment)
+	var m byte
+	if n.DoubleWidth {
+		m |= 1
+	}
+	if n.DoubleHeight {
+		m |= 2
+	}
+	return p.Raw(0x1C, 'p', byte(number), m)
+}
+
+// DefineNVGraphic builds GS ( L function 67, defining a raster graphic in NV memory
+func DefineNVGraphic(key string, img image.Image) []byte {
+	width, height, pixels := imaging.BlackPixels(img)
+	rowBytes := (width + 7) / 8
+
+	params := []byte{48, 67, 48, key[0], key[1], 1,
+		byte(width), byte(width >> 8), byte(height), byte(height >> 8), 49}
+	data := make([]byte, rowBytes*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if pixels[y][x] {
+				data[y*rowBytes+x/8] |= 0x80 >> (x % 8)
+			}
+		}
+	}
+
+	size := len(params) + len(data)
+	var cmd []byte
+	if size <= 0xFFFF {
+		cmd = []byte{0x1D, '(', 'L', byte(size), byte(size >> 8)}
+	} else {
+		// GS 8 L is the same command with a 4 byte length for big images
+		cmd = []byte{0x1D, '8', 'L', byte(size), byte(size >> 8), byte(size >> 16), byte(size >> 24)}
+	}
+	cmd = append(cmd, params...)
+	return append(cmd, data...)
+}
+
+// DefineNVBitImages builds FS q, which replaces all NV bit images at once.
+// Images are numbered from 1 in key order and stored column by column.
+func DefineNVBitImages(images map[string]image.Image) []byte {
+	keys := make([]string, 0, len(images))
+	for key := range images {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	cmd := []byte{0x1C, 'q', byte(len(keys))}
+	for _, key := range keys {
+		width, height, pixels := imaging.BlackPixels(images[key])
+		xBytes := (width + 7) / 8
+		yBytes := (height + 7) / 8
+
+		cmd = append(cmd, byte(xBytes), byte(xBytes>>8), byte(yBytes), byte(yBytes>>8))
+		for x := 0; x < xBytes*8; x++ {
+			for yb := 0; yb < yBytes; yb++ {
+				var b byte
+				for bit := 0; bit < 8; bit++ {
+					y := yb*8 + bit
+					if x < width && y < height && pixels[y][x] {
+						b |= 0x80 >> bit
+					}
+				}
+				cmd = append(cmd, b)
+			}
+		}
+	}
+	return cmd
+}
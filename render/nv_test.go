@@ -0,0 +1,26 @@
+package render
+
+import (
+	"image"
+	"testing"
+
+	"github.com/codaea/simpleprint/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefineNVGraphic(t *testing.T) {
+	cmd := DefineNVGraphic("L1", testutil.Checkerboard(10, 2))
+
+	// 2 bytes per row, 2 rows, plus 11 bytes of parameters
+	assert.Equal(t, []byte{0x1D, '(', 'L', 15, 0, 48, 67, 48, 'L', '1', 1, 10, 0, 2, 0, 49}, cmd[:16])
+	assert.Equal(t, []byte{0xAA, 0x80, 0x55, 0x40}, cmd[16:])
+}
+
+func TestDefineNVBitImages(t *testing.T) {
+	cmd := DefineNVBitImages(map[string]image.Image{"B2": testutil.Checkerboard(8, 8), "A1": testutil.Checkerboard(8, 1)})
+
+	assert.Equal(t, []byte{0x1C, 'q', 2}, cmd[:3])
+	// A1 comes first, 1 byte wide and 1 byte high, stored column by column
+	assert.Equal(t, []byte{1, 0, 1, 0, 0x80, 0x00, 0x80, 0x00, 0x80, 0x00, 0x80, 0x00}, cmd[3:15])
+	assert.Equal(t, []byte{1, 0, 1, 0, 0xAA, 0x55}, cmd[15:21])
+}
@@ -0,0 +1,327 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/boombuler/barcode"
+	"github.com/codaea/simpleprint/receipt"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+var _ Printer = (*Preview)(nil)
+
+// Preview is a printer that draws the receipt instead of printing it. Text is drawn in Go Mono
+// in the character cells of the printer's fonts, codes the printer draws itself are drawn in Go
+// and commands that print nothing, like the drawer and the buzzer, are left out.
+type Preview struct {
+	canvas *image.Gray
+	// y is the top of the next line
+	y int
+	// line is the text printed since the last line ended
+	line []previewChar
+	// style is the last style text was printed in, the height of empty lines
+	style Style
+	faces *Faces
+}
+
+type previewChar struct {
+	r     rune
+	style Style
+}
+
+func NewPreview() (*Preview, error) {
+	p := &Preview{
+		canvas: image.NewGray(image.Rect(0, 0, receipt.PaperWidth, 0)),
+		style:  Style{Font: receipt.FontA, Size: 1},
+	}
+	faces, err := NewFaces(map[string][]byte{"mono": gomono.TTF, "monobold": gomonobold.TTF})
+	if err != nil {
+		return nil, err
+	}
+	p.faces = faces
+	return p, nil
+}
+
+// Faces are the faces of a set of fonts, each made the first time it is used
+type Faces struct {
+	fonts map[string]*opentype.Font
+	faces map[string]font.Face
+}
+
+// NewFaces parses the TrueType fonts ttfs, by the name they are used with
+func NewFaces(ttfs map[string][]byte) (*Faces, error) {
+	f := &Faces{fonts: make(map[string]*opentype.Font), faces: make(map[string]font.Face)}
+	for name, ttf := range ttfs {
+		parsed, err := opentype.Parse(ttf)
+		if err != nil {
+			return nil, err
+		}
+		f.fonts[name] = parsed
+	}
+	return f, nil
+}
+
+// Face returns the face of font name at size, in dots
+func (f *Faces) Face(name string, size float64) font.Face {
+	key := fmt.Sprintf("%s%g", name, size)
+	if face, ok := f.faces[key]; ok {
+		return face
+	}
+	face, err := opentype.NewFace(f.fonts[name], &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		// only fails for invalid options
+		panic(err)
+	}
+	f.faces[key] = face
+	return face
+}
+
+// Paper returns everything printed so far, as long as the receipt
+func (p *Preview) Paper() image.Image {
+	p.endLine()
+	return p.canvas.SubImage(image.Rect(0, 0, receipt.PaperWidth, p.y))
+}
+
+// lineHeight is the height of a line of text in style, font A is 12x24 dots and B and C are 9x17
+func lineHeight(s Style) int {
+	if s.Font == receipt.FontB || s.Font == receipt.FontC {
+		return 17 * max(1, s.Size)
+	}
+	return 24 * max(1, s.Size)
+}
+
+// grow makes the canvas at least height dots below y, new paper is white
+func (p *Preview) grow(height int) {
+	b := p.canvas.Bounds()
+	if p.y+height <= b.Dy() {
+		return
+	}
+	canvas := image.NewGray(image.Rect(0, 0, receipt.PaperWidth, max(p.y+height, 2*b.Dy())))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(canvas, b, p.canvas, image.Point{}, draw.Src)
+	p.canvas = canvas
+}
+
+// face returns the face that fills the character cell of style
+func (p *Preview) face(s Style) font.Face {
+	name := "mono"
+	if s.Bold {
+		name = "monobold"
+	}
+	// Go Mono is 0.6 em wide, so a 12 dot cell takes a 20 dot font
+	return p.faces.Face(name, float64(receipt.CharWidth(s.Font, s.Size))*5/3)
+}
+
+func (p *Preview) write(text string, style Style) {
+	p.style = style
+	if style.CodePage != "" {
+		text = style.CodePage.Printable(text)
+	}
+	for _, r := range text {
+		if r == '\n' {
+			p.newLine()
+			continue
+		}
+		p.line = append(p.line, previewChar{r: r, style: style})
+	}
+}
+
+// newLine ends the line like LF, an empty line is fed in the last style
+func (p *Preview) newLine() {
+	if len(p.line) == 0 {
+		p.grow(lineHeight(p.style))
+		p.y += lineHeight(p.style)
+		return
+	}
+	p.endLine()
+}
+
+// endLine draws the text of the line, wrapped at the paper width like the printer does
+func (p *Preview) endLine() {
+	for len(p.line) > 0 {
+		width, height, n := 0, 0, 0
+		for _, c := range p.line {
+			w := receipt.CharWidth(c.style.Font, c.style.Size)
+			if n > 0 && width+w > receipt.PaperWidth {
+				break
+			}
+			width += w
+			height = max(height, lineHeight(c.style))
+			n++
+		}
+
+		p.grow(height)
+		x := AlignX(width, p.line[0].style.Alignment)
+		for _, c := range p.line[:n] {
+			w := receipt.CharWidth(c.style.Font, c.style.Size)
+			baseline := p.y + height - height/5
+			d := font.Drawer{Dst: p.canvas, Src: image.Black, Face: p.face(c.style), Dot: fixed.P(x, baseline)}
+			d.DrawString(string(c.r))
+			if c.style.Underline {
+				draw.Draw(p.canvas, image.Rect(x, baseline+2, x+w, baseline+2+max(1, c.style.Size)), image.Black, image.Point{}, draw.Src)
+			}
+			x += w
+		}
+		p.y += height
+		p.line = p.line[n:]
+	}
+}
+
+// AlignX is the x position of something width dots wide aligned on the paper
+func AlignX(width int, alignment receipt.AlignmentType) int {
+	switch alignment {
+	case receipt.AlignCenter:
+		return max(0, (receipt.PaperWidth-width)/2)
+	case receipt.AlignRight:
+		return max(0, receipt.PaperWidth-width)
+	}
+	return 0
+}
+
+// draw ends the line and draws img below it
+func (p *Preview) draw(img image.Image, alignment receipt.AlignmentType) {
+	p.endLine()
+	b := img.Bounds()
+	p.grow(b.Dy())
+	x := AlignX(b.Dx(), alignment)
+	draw.Draw(p.canvas, image.Rect(x, p.y, x+b.Dx(), p.y+b.Dy()), img, b.Min, draw.Src)
+	p.y += b.Dy()
+}
+
+// placeholder draws a box with label for what only the printer can draw
+func (p *Preview) placeholder(label string, width, height int, alignment receipt.AlignmentType) {
+	box := image.NewGray(image.Rect(0, 0, min(width, receipt.PaperWidth), height))
+	draw.Draw(box, box.Bounds(), image.Black, image.Point{}, draw.Src)
+	draw.Draw(box, box.Bounds().Inset(2), image.White, image.Point{}, draw.Src)
+	face := p.face(Style{Font: receipt.FontB, Size: 1})
+	d := font.Drawer{Dst: box, Src: image.Black, Face: face}
+	d.Dot = fixed.P((box.Bounds().Dx()-d.MeasureString(label).Ceil())/2, height/2+5)
+	d.DrawString(label)
+	p.draw(box, alignment)
+}
+
+func (p *Preview) Print(text string, style Style) error {
+	p.write(text, style)
+	return nil
+}
+
+func (p *Preview) PrintLn(text string, style Style) error {
+	p.write(text, style)
+	p.newLine()
+	return nil
+}
+
+// Feed prints the line and feeds, like ESC d the line counts as the first one fed
+func (p *Preview) Feed(lines int) error {
+	if len(p.line) > 0 && lines > 0 {
+		p.endLine()
+		lines--
+	}
+	p.grow(lines * lineHeight(p.style))
+	p.y += lines * lineHeight(p.style)
+	return nil
+}
+
+// Cut draws a dashed line where the paper is cut, a partial cut leaves a gap in the middle
+func (p *Preview) Cut(mode receipt.CutMode, feedLines int) error {
+	if mode == receipt.CutNone {
+		return nil
+	}
+	p.Feed(feedLines)
+	p.endLine()
+	p.grow(12)
+	gray := color.Gray{Y: 0x80}
+	for x := 0; x < receipt.PaperWidth; x += 12 {
+		if mode == receipt.CutPartial && x > receipt.PaperWidth/2-36 && x < receipt.PaperWidth/2+36 {
+			continue
+		}
+		draw.Draw(p.canvas, image.Rect(x, p.y+5, x+6, p.y+7), image.NewUniform(gray), image.Point{}, draw.Src)
+	}
+	p.y += 12
+	return nil
+}
+
+func (p *Preview) Image(img image.Image, alignment receipt.AlignmentType) error {
+	p.draw(img, alignment)
+	return nil
+}
+
+// Barcode draws the barcode in Go with its HRI, types only the printer has are a placeholder
+func (p *Preview) Barcode(b receipt.Barcode) error {
+	hri := Style{Font: b.HRIFont, Size: 1, Alignment: b.Alignment}
+	if b.HRIPosition == receipt.HRIAbove || b.HRIPosition == receipt.HRIBoth {
+		p.PrintLn(b.HRI(), hri)
+	}
+	code, err := b.Encode()
+	if err != nil {
+		p.placeholder(string(b.BarcodeType), receipt.PaperWidth/2, b.Height, b.Alignment)
+	} else {
+		scaled, err := barcode.Scale(code, code.Bounds().Dx()*b.ModuleWidth, b.Height)
+		if err != nil {
+			return err
+		}
+		p.draw(scaled, b.Alignment)
+	}
+	if b.HRIPosition == receipt.HRIBelow || b.HRIPosition == receipt.HRIBoth {
+		p.PrintLn(b.HRI(), hri)
+	}
+	return nil
+}
+
+// drawCode draws a 2D code scaled to size dots per module
+func (p *Preview) drawCode(encode func() (barcode.Barcode, error), size int, alignment receipt.AlignmentType) error {
+	code, err := encode()
+	if err != nil {
+		return err
+	}
+	scaled, err := ScaleCode(code, size)
+	if err != nil {
+		return err
+	}
+	p.draw(scaled, alignment)
+	return nil
+}
+
+func (p *Preview) QR(q receipt.QRCode) error {
+	return p.drawCode(q.Encode, q.Size, q.Alignment)
+}
+
+func (p *Preview) PDF417(c receipt.PDF417) error {
+	return p.drawCode(c.Encode, c.Size, c.Alignment)
+}
+
+func (p *Preview) DataMatrix(c receipt.DataMatrix) error {
+	return p.drawCode(c.Encode, c.Size, c.Alignment)
+}
+
+func (p *Preview) Aztec(c receipt.Aztec) error {
+	return p.drawCode(c.Encode, c.Size, c.Alignment)
+}
+
+// NVImage draws a placeholder, the graphic is only stored in the printer
+func (p *Preview) NVImage(n receipt.NVImage) error {
+	width, height := 192, 96
+	if n.DoubleWidth {
+		width *= 2
+	}
+	if n.DoubleHeight {
+		height *= 2
+	}
+	p.placeholder("NV graphic "+n.Key, width, height, n.Alignment)
+	return nil
+}
+
+func (p *Preview) OpenDrawer(d receipt.Drawer) error {
+	return nil
+}
+
+func (p *Preview) Beep(b receipt.Beep) error {
+	return nil
+}
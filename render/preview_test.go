@@ -0,0 +1,74 @@
+package render
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/stretchr/testify/assert"
+)
+
+// darkRows returns the rows of img with at least one dark pixel
+func darkRows(img image.Image) map[int]bool {
+	rows := make(map[int]bool)
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y < 0x80 {
+				rows[y] = true
+				break
+			}
+		}
+	}
+	return rows
+}
+
+func TestPreview_Text(t *testing.T) {
+	p, err := NewPreview()
+	assert.NoError(t, err)
+
+	assert.NoError(t, p.PrintLn("Hello", Style{Font: receipt.FontA, Size: 1}))
+	assert.NoError(t, p.PrintLn("", Style{Font: receipt.FontA, Size: 1}))
+	assert.NoError(t, p.PrintLn("Big", Style{Font: receipt.FontA, Size: 2, Alignment: receipt.AlignRight}))
+	img := p.Paper()
+	assert.Equal(t, image.Rect(0, 0, receipt.PaperWidth, 24+24+48), img.Bounds())
+
+	rows := darkRows(img)
+	assert.True(t, rows[12], "the first line has text")
+	assert.False(t, rows[36], "the second line is empty")
+	assert.True(t, rows[72], "the third line has text")
+
+	// right aligned text ends at the edge of the paper
+	assert.Equal(t, color.Gray{Y: 255}, img.At(10, 72))
+}
+
+func TestPreview_Wraps(t *testing.T) {
+	p, _ := NewPreview()
+	p.PrintLn("0123456789012345678901234567890123456789012345678901234567890", Style{Font: receipt.FontA, Size: 1})
+	assert.Equal(t, 48, receipt.CharsPerLine(receipt.FontA, 1))
+	assert.Equal(t, 48, p.Paper().Bounds().Dy(), "61 characters take 2 lines of 48")
+}
+
+func TestPreview_Receipt(t *testing.T) {
+	var req receipt.Request
+	err := json.Unmarshal([]byte(`{"cut": "partial", "receipt": [
+		{"type": "line", "content": "Coffee Shop", "alignment": "center", "bold": true},
+		{"type": "columns", "columns": [{"content": "Latte"}, {"content": "$4.50", "width": 8, "alignment": "right"}]},
+		{"type": "barcode", "code": "4006381333931", "barcode_type": "EAN13"},
+		{"type": "barcode", "code": "0950110153000", "barcode_type": "GS1_DATABAR"},
+		{"type": "qr", "code": "https://example.com"},
+		{"type": "aztec", "code": "ticket 42"},
+		{"type": "nv_image", "key": "L1", "double_width": true},
+		{"type": "drawer"},
+		{"type": "feed", "lines": 2}
+	]}`), &req)
+	assert.NoError(t, err)
+
+	p, _ := NewPreview()
+	assert.NoError(t, Print(p, req))
+	img := p.Paper()
+	assert.Equal(t, receipt.PaperWidth, img.Bounds().Dx())
+	assert.Greater(t, img.Bounds().Dy(), 24+24+100+100+96)
+}
@@ -0,0 +1,353 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/mect/go-escpos"
+)
+
+// Style is how text is printed
+type Style struct {
+	Font      receipt.FontType
+	Size      int
+	Alignment receipt.AlignmentType
+	Bold      bool
+	Underline bool
+	// CodePage is the table text is encoded in, go-escpos's ISO-8859-15 if empty
+	CodePage receipt.CodePage
+}
+
+// Printer is what receipts are rendered to. ESCPOS sends them to a thermal printer,
+// other printers can draw a preview or record what was printed.
+type Printer interface {
+	// Print prints text in style, the next text continues the line
+	Print(text string, style Style) error
+	// PrintLn prints text in style and ends the line
+	PrintLn(text string, style Style) error
+	Feed(lines int) error
+	// Cut feeds feedLines and cuts the paper, CutNone does neither
+	Cut(mode receipt.CutMode, feedLines int) error
+	// Image prints an image that is already scaled to the paper and black and white
+	Image(img image.Image, alignment receipt.AlignmentType) error
+	Barcode(b receipt.Barcode) error
+	// QR, PDF417, DataMatrix and Aztec print 2D codes the printer draws itself,
+	// codes rendered as raster images are printed with Image
+	QR(q receipt.QRCode) error
+	PDF417(c receipt.PDF417) error
+	DataMatrix(c receipt.DataMatrix) error
+	Aztec(c receipt.Aztec) error
+	NVImage(n receipt.NVImage) error
+	OpenDrawer(d receipt.Drawer) error
+	Beep(b receipt.Beep) error
+}
+
+var _ Printer = (*ESCPOS)(nil)
+
+// Features are what a printer can do besides printing text and images
+type Features struct {
+	Cutter bool `json:"cutter"`
+	Drawer bool `json:"drawer"`
+	// QR is the QR code command, without it QR codes are printed as images
+	QR bool `json:"qr"`
+	// CodePages are the tables ESC t can select, any of them if empty
+	CodePages []receipt.CodePage `json:"code_pages,omitempty"`
+}
+
+// AllFeatures is a printer that can do everything
+var AllFeatures = Features{Cutter: true, Drawer: true, QR: true}
+
+// ErrUnsupported is returned for what the printer's Features don't have
+var ErrUnsupported = errors.New("not supported by the printer")
+
+var errNoDrawer = fmt.Errorf("cash drawer: %w", ErrUnsupported)
+
+// checkCodePage returns an error if text can't be printed in c, any code page can if CodePages is empty
+func (f Features) checkCodePage(c receipt.CodePage) error {
+	if c != "" && len(f.CodePages) > 0 && !slices.Contains(f.CodePages, c) {
+		return fmt.Errorf("code page %s: %w", c, ErrUnsupported)
+	}
+	return nil
+}
+
+// ErrTooWide is returned for a code drawn in Go that doesn't fit on the paper
+var ErrTooWide = errors.New("more than the paper width")
+
+// ESCPOS is a printer that speaks ESC/POS. It uses go-escpos for the commands it has
+// and keeps the device around to send the ones it doesn't.
+type ESCPOS struct {
+	p  *escpos.Printer
+	rw io.ReadWriteCloser
+	// codePage is the table selected with ESC t, empty for ISO-8859-15 which Init selects
+	codePage receipt.CodePage
+	// Features are what the printer can do, AllFeatures unless they are set
+	Features Features
+}
+
+// usbDevice stops a write to a stuck printer from blocking forever, like go-escpos does for its own USB printers
+type usbDevice struct {
+	*os.File
+}
+
+func (d usbDevice) Write(b []byte) (int, error) {
+	d.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return d.File.Write(b)
+}
+
+// Open opens the printer at devpath, or the first /dev/usb/lp* device if devpath is empty
+func Open(devpath string) (*ESCPOS, error) {
+	if devpath == "" {
+		devices, err := ListDevices()
+		if err != nil {
+			return nil, err
+		}
+		if len(devices) == 0 {
+			return nil, escpos.ErrorNoDevicesFound
+		}
+		devpath = devices[0].Path
+	}
+
+	f, err := os.OpenFile(devpath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return NewESCPOS(usbDevice{f})
+}
+
+// ParseURI splits a printer URI into its transport, usb or tcp, and the device path or network address.
+// An empty URI or usb: is the first USB printer, usb:///dev/usb/lp1, file:///dev/usb/lp1 or a path
+// is a device file and tcp://host:port a network printer, on port 9100 if it has none.
+func ParseURI(uri string) (transport, target string, err error) {
+	if uri == "" || uri == "usb:" {
+		return "usb", "", nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		// a path, or a Windows style drive letter
+		return "usb", uri, nil
+	}
+
+	switch u.Scheme {
+	case "usb", "file":
+		return "usb", u.Path, nil
+	case "tcp":
+		if u.Hostname() == "" {
+			return "", "", fmt.Errorf("invalid printer URI %s: tcp needs a host", uri)
+		}
+		if u.Port() == "" {
+			return "tcp", net.JoinHostPort(u.Hostname(), "9100"), nil
+		}
+		return "tcp", u.Host, nil
+	}
+	return "", "", fmt.Errorf("invalid printer URI %s: the scheme must be usb, file or tcp", uri)
+}
+
+// netDevice stops a write to a network printer that stopped answering from blocking forever
+type netDevice struct {
+	net.Conn
+}
+
+func (d netDevice) Write(b []byte) (int, error) {
+	d.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return d.Conn.Write(b)
+}
+
+// OpenURI opens the printer at uri, see ParseURI
+func OpenURI(uri string) (*ESCPOS, error) {
+	transport, target, err := ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if transport == "tcp" {
+		conn, err := net.DialTimeout("tcp", target, 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return NewESCPOS(netDevice{conn})
+	}
+	return Open(target)
+}
+
+// NewESCPOS is a printer that sends its commands to rw
+func NewESCPOS(rw io.ReadWriteCloser) (*ESCPOS, error) {
+	p, err := escpos.NewPrinterByRW(rw)
+	if err != nil {
+		return nil, err
+	}
+	return &ESCPOS{p: p, rw: rw, Features: AllFeatures}, nil
+}
+
+// Init resets the printer to its defaults
+func (p *ESCPOS) Init() error {
+	p.codePage = ""
+	return p.p.Init()
+}
+
+// Smooth turns smoothing of large text on or off
+func (p *ESCPOS) Smooth(enabled bool) error {
+	return p.p.Smooth(enabled)
+}
+
+func (p *ESCPOS) Close() error {
+	return p.rw.Close()
+}
+
+// Raw sends ESC/POS bytes to the printer as they are
+func (p *ESCPOS) Raw(cmd ...byte) error {
+	_, err := p.rw.Write(cmd)
+	return err
+}
+
+// Align sets the alignment of what is printed next
+func (p *ESCPOS) Align(a receipt.AlignmentType) error {
+	return p.p.Align(escposAlignment(a))
+}
+
+// Bold turns emphasized printing (ESC E) on or off
+func (p *ESCPOS) Bold(enabled bool) error {
+	if enabled {
+		return p.Raw(0x1B, 'E', 1)
+	}
+	return p.Raw(0x1B, 'E', 0)
+}
+
+// setStyle sends every part of the style, so nothing is left over from the text before
+func (p *ESCPOS) setStyle(s Style) {
+	p.p.Font(escposFont(s.Font))
+	p.p.Align(escposAlignment(s.Alignment))
+	p.p.Size(uint8(s.Size), uint8(s.Size))
+	p.p.Underline(s.Underline)
+	p.Bold(s.Bold)
+	// ESC t is only sent when the table changes, text without a code page goes back to ISO-8859-15
+	if s.CodePage != p.codePage {
+		p.Raw(0x1B, 't', escposCodePage(s.CodePage))
+		p.codePage = s.CodePage
+	}
+}
+
+// Print prints text, in the code page of style if it has one. Without one go-escpos encodes it in ISO-8859-15.
+func (p *ESCPOS) Print(text string, style Style) error {
+	if err := p.Features.checkCodePage(style.CodePage); err != nil {
+		return err
+	}
+	p.setStyle(style)
+	if style.CodePage != "" {
+		return p.Raw(style.CodePage.Encode(text)...)
+	}
+	return p.p.Print(text)
+}
+
+func (p *ESCPOS) PrintLn(text string, style Style) error {
+	if err := p.Print(text, style); err != nil {
+		return err
+	}
+	return p.Raw('\n')
+}
+
+func (p *ESCPOS) Feed(lines int) error {
+	return p.p.Feed(lines)
+}
+
+// Cut feeds feedLines and cuts the paper, a partial cut leaves a bit of paper attached.
+// Printers without a cutter only feed, so the paper can be torn off.
+func (p *ESCPOS) Cut(mode receipt.CutMode, feedLines int) error {
+	if mode == receipt.CutNone {
+		return nil
+	}
+	if feedLines > 0 {
+		if err := p.p.Feed(feedLines); err != nil {
+			return err
+		}
+	}
+	if !p.Features.Cutter {
+		return nil
+	}
+	if mode == receipt.CutPartial {
+		return p.Raw(0x1D, 'V', 'B', '0')
+	}
+	return p.p.Cut()
+}
+
+func (p *ESCPOS) Image(img image.Image, alignment receipt.AlignmentType) error {
+	p.Align(alignment)
+	return p.p.Image(img)
+}
+
+// OpenDrawer sends the drawer kick pulse with ESC p
+func (p *ESCPOS) OpenDrawer(d receipt.Drawer) error {
+	if !p.Features.Drawer {
+		return errNoDrawer
+	}
+	var m byte // pin 2
+	if d.Pin == 5 {
+		m = 1
+	}
+	return p.Raw(0x1B, 'p', m, byte(d.OnMs/2), byte(d.OffMs/2))
+}
+
+// Beep sounds the buzzer
+func (p *ESCPOS) Beep(b receipt.Beep) error {
+	if b.Method == receipt.BeepESCParenA {
+		return p.Raw(0x1B, '(', 'A', 4, 0, 48, 49, byte(b.Count), byte(b.DurationMs/100))
+	}
+	return p.Raw(0x1B, 'B', byte(b.Count), byte(b.DurationMs/50))
+}
+
+func escposFont(f receipt.FontType) escpos.Font {
+	switch f {
+	case receipt.FontB:
+		return escpos.FontB
+	case receipt.FontC:
+		return escpos.FontC
+	default:
+		return escpos.FontA
+	}
+}
+
+// isoTable is the ESC t number of ISO-8859-15, the table go-escpos selects in Init and encodes text in
+const isoTable = 40
+
+// escposCodePage is the ESC t number of c, the tables of most Epson compatible printers.
+// No code page is ISO-8859-15.
+func escposCodePage(c receipt.CodePage) byte {
+	switch c {
+	case "":
+		return isoTable
+	case receipt.CodePagePC850:
+		return 2
+	case receipt.CodePagePC860:
+		return 3
+	case receipt.CodePagePC863:
+		return 4
+	case receipt.CodePagePC865:
+		return 5
+	case receipt.CodePageWPC1252:
+		return 16
+	case receipt.CodePagePC866:
+		return 17
+	case receipt.CodePagePC852:
+		return 18
+	case receipt.CodePagePC858:
+		return 19
+	}
+	return 0
+}
+
+func escposAlignment(a receipt.AlignmentType) escpos.Alignment {
+	switch a {
+	case receipt.AlignRight:
+		return escpos.AlignRight
+	case receipt.AlignCenter:
+		return escpos.AlignCenter
+	default:
+		return escpos.AlignLeft
+	}
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/codaea/simpleprint/internal/testutil"
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBufferPrinter() (*ESCPOS, *testutil.BufferDevice) {
+	dev := &testutil.BufferDevice{}
+	p, _ := NewESCPOS(dev)
+	return p, dev
+}
+
+func TestESCPOS_OpenDrawer(t *testing.T) {
+	p, dev := newBufferPrinter()
+
+	var d receipt.Drawer
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "drawer"}`), &d))
+	assert.NoError(t, p.OpenDrawer(d))
+	assert.Equal(t, []byte{0x1B, 'p', 0, 50, 250}, dev.Bytes())
+
+	dev.Reset()
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "drawer", "pin": 5, "on_ms": 60, "off_ms": 120}`), &d))
+	assert.NoError(t, p.OpenDrawer(d))
+	assert.Equal(t, []byte{0x1B, 'p', 1, 30, 60}, dev.Bytes())
+
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"pin": 3}`), &d), "invalid pin")
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"on_ms": 600}`), &d), "invalid on_ms")
+}
+
+func TestESCPOS_Beep(t *testing.T) {
+	p, dev := newBufferPrinter()
+
+	var b receipt.Beep
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "beep", "count": 3, "duration_ms": 200}`), &b))
+	assert.NoError(t, p.Beep(b))
+	assert.Equal(t, []byte{0x1B, 'B', 3, 4}, dev.Bytes())
+
+	dev.Reset()
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "beep", "count": 20, "duration_ms": 500, "method": "esc_paren_a"}`), &b))
+	assert.NoError(t, p.Beep(b))
+	assert.Equal(t, []byte{0x1B, '(', 'A', 4, 0, 48, 49, 20, 5}, dev.Bytes())
+
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"count": 20}`), &b), "invalid count")
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"method": "bell"}`), &b), "invalid method")
+}
+
+func TestESCPOS_Cut(t *testing.T) {
+	p, dev := newBufferPrinter()
+
+	assert.NoError(t, p.Cut(receipt.CutFull, 0))
+	assert.Equal(t, "\x1dVA0", dev.String())
+
+	dev.Reset()
+	assert.NoError(t, p.Cut(receipt.CutPartial, 3))
+	assert.Equal(t, "\x1bd\x03\x1dVB0", dev.String())
+
+	dev.Reset()
+	assert.NoError(t, p.Cut(receipt.CutNone, 3))
+	assert.Empty(t, dev.String())
+}
+
+func TestESCPOS_CodePage(t *testing.T) {
+	p, dev := newBufferPrinter()
+
+	assert.NoError(t, p.PrintLn("Café", Style{Font: receipt.FontA, Size: 1, CodePage: receipt.CodePagePC850}))
+	assert.Contains(t, dev.String(), "\x1bt\x02Caf\x82\n")
+
+	// ESC t is only sent again when the table changes
+	dev.Reset()
+	assert.NoError(t, p.PrintLn("é", Style{Font: receipt.FontA, Size: 1, CodePage: receipt.CodePagePC850}))
+	assert.NotContains(t, dev.String(), "\x1bt")
+	dev.Reset()
+	// text without a code page is encoded in ISO-8859-15 by go-escpos, so its table is selected again
+	assert.NoError(t, p.PrintLn("é", Style{Font: receipt.FontA, Size: 1}))
+	assert.Contains(t, dev.String(), "\x1bt(\xe9\n")
+}
+
+func TestParseURI(t *testing.T) {
+	for uri, want := range map[string][2]string{
+		"":                        {"usb", ""},
+		"usb:":                    {"usb", ""},
+		"usb://":                  {"usb", ""},
+		"/dev/usb/lp1":            {"usb", "/dev/usb/lp1"},
+		"usb:///dev/usb/lp1":      {"usb", "/dev/usb/lp1"},
+		"file:///dev/usb/lp1":     {"usb", "/dev/usb/lp1"},
+		"tcp://192.168.1.50":      {"tcp", "192.168.1.50:9100"},
+		"tcp://printer.lan:10000": {"tcp", "printer.lan:10000"},
+	} {
+		transport, target, err := ParseURI(uri)
+		assert.NoError(t, err, uri)
+		assert.Equal(t, want, [2]string{transport, target}, uri)
+	}
+
+	for _, uri := range []string{"tcp://", "bluetooth://printer"} {
+		_, _, err := ParseURI(uri)
+		assert.Error(t, err, uri)
+	}
+}
+
+func TestESCPOS_Features(t *testing.T) {
+	p, dev := newBufferPrinter()
+	p.Features = Features{CodePages: []receipt.CodePage{receipt.CodePagePC437}}
+
+	// without a cutter the paper is only fed
+	assert.NoError(t, p.Cut(receipt.CutFull, 3))
+	assert.Equal(t, []byte{0x1B, 'd', 3}, dev.Bytes())
+
+	assert.ErrorIs(t, p.OpenDrawer(receipt.Drawer{Pin: 2, OnMs: 100, OffMs: 100}), ErrUnsupported)
+	assert.ErrorIs(t, p.Print("x", Style{CodePage: receipt.CodePagePC866}), ErrUnsupported)
+
+	// QR codes are printed as images
+	dev.Reset()
+	var q receipt.QRCode
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "qr", "code": "https://example.com"}`), &q))
+	assert.NoError(t, p.QR(q))
+	assert.NotContains(t, dev.String(), "\x1d(k")
+	assert.Contains(t, dev.String(), "\x1dv0")
+}
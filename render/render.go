@@ -0,0 +1,213 @@
+// Package render prints decoded receipts on a Printer. ESCPOS is the Printer of thermal printers,
+// the layout that doesn't depend on the printer, like columns, copies, variables and codes
+// drawn as images, is done here.
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/boombuler/barcode"
+	"github.com/codaea/simpleprint/receipt"
+	"golang.org/x/image/draw"
+)
+
+// CutFeedLines is how many lines are fed before cutting when a request doesn't set feed_before_cut,
+// so the last printed line clears the cutter
+var CutFeedLines = 0
+
+// FeedBeforeCut returns the lines to feed before cutting for req
+func FeedBeforeCut(req receipt.Request) int {
+	if req.FeedBeforeCut != nil {
+		return *req.FeedBeforeCut
+	}
+	return CutFeedLines
+}
+
+// Print prints every copy of the receipt, each cut separately. It stops at the first item that fails,
+// without cutting, so what was printed before it is left on the printer. Check finds what a printer
+// can't print before anything is sent.
+func Print(p Printer, req receipt.Request) error {
+	for n := 0; n < max(1, req.Copies); n++ {
+		vars := req.VariablesFor(n)
+		for i, item := range req.Receipt {
+			if err := printItem(p, receipt.WithVariables(item, vars), FeedBeforeCut(req)); err != nil {
+				return fmt.Errorf("receipt item %d: %w", i, err)
+			}
+		}
+
+		if err := p.Cut(req.Cut, FeedBeforeCut(req)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check returns an error for every item of req a printer with features f can't print: code pages
+// it doesn't have, the cash drawer when it has none and codes drawn in Go that are wider than the paper
+func Check(f Features, req receipt.Request) error {
+	var errs []error
+	failed := make(map[int]bool)
+	for n := 0; n < max(1, req.Copies); n++ {
+		vars := req.VariablesFor(n)
+		for i, item := range req.Receipt {
+			if failed[i] {
+				continue
+			}
+			if err := checkItem(f, receipt.WithVariables(item, vars)); err != nil {
+				errs = append(errs, fmt.Errorf("receipt item %d: %w", i, err))
+				failed[i] = true
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkItem checks one receipt item against the features of the printer
+func checkItem(f Features, item receipt.Item) error {
+	switch v := item.(type) {
+	case receipt.Line:
+		return f.checkCodePage(v.CodePage)
+	case receipt.Text:
+		return f.checkCodePage(v.CodePage)
+	case receipt.Drawer:
+		if !f.Drawer {
+			return errNoDrawer
+		}
+	case receipt.QRCode:
+		if v.Render == receipt.RenderRaster || !f.QR {
+			return checkRaster(v.Encode, v.Size)
+		}
+	case receipt.PDF417:
+		if v.Render == receipt.RenderRaster {
+			return checkRaster(v.Encode, v.Size)
+		}
+	case receipt.DataMatrix:
+		if v.Render == receipt.RenderRaster {
+			return checkRaster(v.Encode, v.Size)
+		}
+	case receipt.Aztec:
+		if v.Render == receipt.RenderRaster {
+			return checkRaster(v.Encode, v.Size)
+		}
+	}
+	return nil
+}
+
+// checkRaster checks a 2D code drawn in Go fits on the paper
+func checkRaster(encode func() (barcode.Barcode, error), size int) error {
+	code, err := encode()
+	if err != nil {
+		return err
+	}
+	_, err = ScaleCode(code, size)
+	return err
+}
+
+// printItem prints one receipt item
+func printItem(p Printer, item receipt.Item, feedBeforeCut int) error {
+	switch v := item.(type) {
+	case receipt.Line:
+		return p.PrintLn(v.Content, Style{Font: v.Font, Size: v.FontSize, Alignment: v.Alignment, Bold: v.Bold, Underline: v.Underline, CodePage: v.CodePage})
+	case receipt.Text:
+		return p.Print(v.Content, Style{Font: v.Font, Size: v.FontSize, Alignment: v.Alignment, Bold: v.Bold, Underline: v.Underline, CodePage: v.CodePage})
+	case receipt.Columns:
+		return p.PrintLn(v.Layout(), Style{Font: v.Font, Size: v.FontSize, Alignment: receipt.AlignLeft, Bold: v.Bold})
+	case receipt.Feed:
+		return p.Feed(v.Lines)
+	case receipt.Cut:
+		return p.Cut(v.Mode, feedBeforeCut)
+	case receipt.Drawer:
+		return p.OpenDrawer(v)
+	case receipt.Beep:
+		return p.Beep(v)
+	case receipt.Barcode:
+		return p.Barcode(v)
+	case receipt.QRCode:
+		if v.Render == receipt.RenderRaster {
+			return printQRRaster(p, v)
+		}
+		return p.QR(v)
+	case receipt.PDF417:
+		if v.Render == receipt.RenderRaster {
+			return printRaster(p, v.Encode, v.Size, v.Alignment)
+		}
+		return p.PDF417(v)
+	case receipt.DataMatrix:
+		if v.Render == receipt.RenderRaster {
+			return printRaster(p, v.Encode, v.Size, v.Alignment)
+		}
+		return p.DataMatrix(v)
+	case receipt.Aztec:
+		if v.Render == receipt.RenderRaster {
+			return printRaster(p, v.Encode, v.Size, v.Alignment)
+		}
+		return p.Aztec(v)
+	case receipt.Image:
+		return p.Image(v.Bitmap(), v.Alignment)
+	case receipt.NVImage:
+		return p.NVImage(v)
+	}
+	return fmt.Errorf("unknown receipt item %T", item)
+}
+
+// ScaleCode scales a code drawn in Go to size dots per module
+func ScaleCode(code barcode.Barcode, size int) (barcode.Barcode, error) {
+	b := code.Bounds()
+	if b.Dx()*size > receipt.PaperWidth {
+		return nil, fmt.Errorf("code is %d dots wide, %w of %d. Use a smaller size", b.Dx()*size, ErrTooWide, receipt.PaperWidth)
+	}
+	return barcode.Scale(code, b.Dx()*size, b.Dy()*size)
+}
+
+// printRaster draws a 2D code in Go, scales it and prints it as an image
+func printRaster(p Printer, encode func() (barcode.Barcode, error), size int, alignment receipt.AlignmentType) error {
+	code, err := encode()
+	if err != nil {
+		return err
+	}
+	scaled, err := ScaleCode(code, size)
+	if err != nil {
+		return err
+	}
+	return p.Image(receipt.Image{Img: scaled}.Bitmap(), alignment)
+}
+
+func printQRRaster(p Printer, q receipt.QRCode) error {
+	if q.LogoImg == nil {
+		return printRaster(p, q.Encode, q.Size, q.Alignment)
+	}
+	code, err := q.Encode()
+	if err != nil {
+		return err
+	}
+	scaled, err := ScaleCode(code, q.Size)
+	if err != nil {
+		return err
+	}
+	return p.Image(receipt.Image{Img: overlayLogo(scaled, q.LogoImg, q.Size)}.Bitmap(), q.Alignment)
+}
+
+// overlayLogo draws logo in the centre of a QR code on a white box,
+// covering at most a fifth of its width so level H can still recover the data
+func overlayLogo(code image.Image, logo image.Image, moduleSize int) image.Image {
+	b := code.Bounds()
+	out := image.NewRGBA(b)
+	draw.Draw(out, b, code, b.Min, draw.Src)
+
+	maxSize := b.Dx() / 5
+	lb := logo.Bounds()
+	width, height := maxSize, maxSize*lb.Dy()/lb.Dx()
+	if height > maxSize {
+		width, height = maxSize*lb.Dx()/lb.Dy(), maxSize
+	}
+
+	center := image.Pt(b.Min.X+b.Dx()/2, b.Min.Y+b.Dy()/2)
+	logoRect := image.Rect(center.X-width/2, center.Y-height/2, center.X-width/2+width, center.Y-height/2+height)
+	// one module of white around the logo keeps it apart from the code
+	draw.Draw(out, logoRect.Inset(-moduleSize), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(out, logoRect, logo, lb, draw.Over, nil)
+	return out
+}
@@ -0,0 +1,153 @@
+package render
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrint_Cut(t *testing.T) {
+	p, dev := newBufferPrinter()
+
+	var req receipt.Request
+	err := json.Unmarshal([]byte(`
+	{
+		"cut": "none",
+		"feed_before_cut": 2,
+		"receipt": [
+			{"type": "feed", "lines": 1},
+			{"type": "cut", "mode": "partial"},
+			{"type": "feed", "lines": 1}
+		]
+	}`), &req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, Print(p, req))
+	assert.Equal(t, "\x1bd\x01\x1bd\x02\x1dVB0\x1bd\x01", dev.String())
+}
+
+func TestPrint_StopsAtFirstError(t *testing.T) {
+	p, dev := newBufferPrinter()
+	p.Features = Features{}
+
+	req := receipt.Request{Receipt: []receipt.Item{receipt.Feed{Lines: 1}, receipt.Drawer{Pin: 2}, receipt.Feed{Lines: 2}}, Cut: receipt.CutFull}
+	assert.ErrorIs(t, Print(p, req), ErrUnsupported)
+	assert.Equal(t, "\x1bd\x01", dev.String(), "nothing after the item that failed is printed, not even the cut")
+}
+
+func TestCheck(t *testing.T) {
+	var wide, fits receipt.PDF417
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "pdf417", "code": "M1DOE/JOHN", "render": "raster", "size": 8}`), &wide))
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "pdf417", "code": "M1DOE/JOHN", "render": "raster", "size": 2}`), &fits))
+	req := receipt.Request{Receipt: []receipt.Item{
+		receipt.Line{Content: "x", CodePage: receipt.CodePagePC437},
+		receipt.Line{Content: "x", CodePage: receipt.CodePagePC866},
+		receipt.Drawer{Pin: 2},
+		wide,
+		fits,
+	}, Copies: 2}
+
+	err := Check(Features{CodePages: []receipt.CodePage{receipt.CodePagePC437}}, req)
+	assert.ErrorIs(t, err, ErrUnsupported)
+	assert.ErrorIs(t, err, ErrTooWide)
+	assert.Equal(t, "receipt item 1: code page PC866: not supported by the printer\n"+
+		"receipt item 2: cash drawer: not supported by the printer\n"+
+		"receipt item 3: code is 960 dots wide, more than the paper width of 576. Use a smaller size", err.Error())
+
+	assert.NoError(t, Check(AllFeatures, receipt.Request{Receipt: []receipt.Item{req.Receipt[1], req.Receipt[2], fits}}))
+}
+
+func TestFeedBeforeCut(t *testing.T) {
+	CutFeedLines = 4
+	defer func() { CutFeedLines = 0 }()
+
+	var req receipt.Request
+	assert.NoError(t, json.Unmarshal([]byte(`{"receipt": [{"type": "cut"}]}`), &req))
+	assert.Equal(t, receipt.CutFull, req.Cut)
+	assert.Equal(t, receipt.CutFull, req.Receipt[0].(receipt.Cut).Mode)
+	assert.Equal(t, 4, FeedBeforeCut(req))
+
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"cut": "half", "receipt": []}`), &req), "invalid cut")
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"receipt": [{"type": "cut", "mode": "half"}]}`), &req), "/receipt/0/mode")
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"feed_before_cut": -1, "receipt": []}`), &req), "invalid feed_before_cut")
+}
+
+func TestPrint_Copies(t *testing.T) {
+	p, dev := newBufferPrinter()
+
+	var req receipt.Request
+	err := json.Unmarshal([]byte(`
+	{
+		"variables": {"store": "Coffee Shop"},
+		"copy_variables": [{"copy": "CUSTOMER COPY"}, {"copy": "MERCHANT COPY", "store": "Coffee Shop #2"}],
+		"receipt": [
+			{"type": "text", "content": "{{store}} {{copy}} {{copy_number}}/{{copies}} {{unknown}}"}
+		]
+	}`), &req)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, req.Copies)
+
+	assert.NoError(t, Print(p, req))
+	out := dev.String()
+	first := "Coffee Shop CUSTOMER COPY 1/2 {{unknown}}\x1dVA0"
+	second := "Coffee Shop #2 MERCHANT COPY 2/2 {{unknown}}\x1dVA0"
+	assert.Contains(t, out, first)
+	assert.Contains(t, out, second)
+	assert.Less(t, strings.Index(out, first), strings.Index(out, second))
+}
+
+func TestPrintItem_QRRasterWithLogo(t *testing.T) {
+	logo := image.NewGray(image.Rect(0, 0, 40, 20))
+	receipt.LoadAsset = func(name string) (image.Image, error) { return logo, nil }
+	defer func() { receipt.LoadAsset = nil }()
+
+	var q receipt.QRCode
+	err := json.Unmarshal([]byte(`{"type": "qr", "code": "https://example.com/receipt/12345", "size": 8, "render": "raster", "logo": "logo"}`), &q)
+	assert.NoError(t, err)
+	assert.Equal(t, receipt.QRLevelH, q.ErrorCorrection)
+
+	p, dev := newBufferPrinter()
+	assert.NoError(t, printItem(p, q, 0))
+	assert.Contains(t, dev.String(), "\x1dv0\x00")
+	assert.NotContains(t, dev.String(), "\x1d(k")
+}
+
+func TestOverlayLogo(t *testing.T) {
+	code := image.NewGray(image.Rect(0, 0, 100, 100))
+	for i := range code.Pix {
+		code.Pix[i] = 0x80
+	}
+	logo := image.NewGray(image.Rect(0, 0, 10, 10))
+	out := overlayLogo(code, logo, 2)
+
+	// gray code, white border around the logo and the black logo in the middle
+	assert.Equal(t, color.RGBA{0x80, 0x80, 0x80, 255}, out.At(5, 5))
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, out.At(50, 39))
+	assert.Equal(t, color.RGBA{0, 0, 0, 255}, out.At(50, 50))
+}
+
+func TestPrintItem_RasterSymbols(t *testing.T) {
+	p, dev := newBufferPrinter()
+
+	var dm receipt.DataMatrix
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "datamatrix", "code": "(01)09501101530003", "render": "raster"}`), &dm))
+	assert.NoError(t, printItem(p, dm, 0))
+	assert.Contains(t, dev.String(), "\x1dv0\x00", "raster codes are printed as images")
+	assert.NotContains(t, dev.String(), "\x1d(k")
+
+	dev.Reset()
+	var az receipt.Aztec
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "aztec", "code": "ticket 42", "render": "raster", "size": 3}`), &az))
+	assert.NoError(t, printItem(p, az, 0))
+	assert.Contains(t, dev.String(), "\x1dv0\x00")
+
+	dev.Reset()
+	var pdf receipt.PDF417
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "pdf417", "code": "M1DOE/JOHN", "render": "raster", "size": 8}`), &pdf))
+	assert.ErrorContains(t, printItem(p, pdf, 0), "more than the paper width")
+}
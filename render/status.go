@@ -0,0 +1,98 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Status is what the printer reports with the DLE EOT real-time status commands
+type Status struct {
+	Online       bool `json:"online"`
+	CoverOpen    bool `json:"cover_open"`
+	PaperNearEnd bool `json:"paper_near_end"`
+	PaperOut     bool `json:"paper_out"`
+	CutterError  bool `json:"cutter_error"`
+	// Error is set for errors that stop printing, the cutter's included
+	Error bool `json:"error"`
+}
+
+// Problems describes everything that is wrong, nothing if the printer is ready
+func (s Status) Problems() []string {
+	var problems []string
+	if !s.Online {
+		problems = append(problems, "offline")
+	}
+	if s.CoverOpen {
+		problems = append(problems, "cover open")
+	}
+	if s.PaperOut {
+		problems = append(problems, "out of paper")
+	} else if s.PaperNearEnd {
+		problems = append(problems, "paper near end")
+	}
+	if s.CutterError {
+		problems = append(problems, "cutter error")
+	} else if s.Error {
+		problems = append(problems, "error")
+	}
+	return problems
+}
+
+// StatusTimeout is how long Status waits for each reply
+var StatusTimeout = 2 * time.Second
+
+var errNoReply = errors.New("the printer didn't reply")
+
+// Status asks for the printer status, the offline and error causes and the paper sensor status.
+// Printers that can't send data back time out.
+func (p *ESCPOS) Status() (Status, error) {
+	var replies [4]byte
+	for i := range replies {
+		if err := p.Raw(0x10, 0x04, byte(i+1)); err != nil {
+			return Status{}, err
+		}
+		reply, err := p.readByte()
+		if err != nil {
+			return Status{}, fmt.Errorf("status %d: %w", i+1, err)
+		}
+		replies[i] = reply
+	}
+
+	return Status{
+		Online:       replies[0]&0x08 == 0,
+		CoverOpen:    replies[1]&0x04 != 0,
+		PaperNearEnd: replies[3]&0x0C != 0,
+		PaperOut:     replies[1]&0x20 != 0 || replies[3]&0x60 != 0,
+		CutterError:  replies[2]&0x08 != 0,
+		Error:        replies[1]&0x40 != 0,
+	}, nil
+}
+
+// readByte reads a reply, the read is left behind if it doesn't finish in StatusTimeout
+func (p *ESCPOS) readByte() (byte, error) {
+	type result struct {
+		b   byte
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		buf := make([]byte, 1)
+		n, err := p.rw.Read(buf)
+		if n == 0 && err == nil {
+			err = io.EOF
+		}
+		done <- result{buf[0], err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return 0, fmt.Errorf("%w: %v", errNoReply, r.err)
+		}
+		return r.b, nil
+	case <-time.After(StatusTimeout):
+		return 0, fmt.Errorf("%w in %s", errNoReply, StatusTimeout)
+	}
+}
@@ -0,0 +1,70 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// statusDevice replies to DLE EOT n with replies[n-1]
+type statusDevice struct {
+	sent    bytes.Buffer
+	replies []byte
+	pending bytes.Buffer
+}
+
+func (d *statusDevice) Write(b []byte) (int, error) {
+	if len(b) == 3 && b[0] == 0x10 && b[1] == 0x04 {
+		d.pending.WriteByte(d.replies[b[2]-1])
+	}
+	return d.sent.Write(b)
+}
+
+func (d *statusDevice) Read(b []byte) (int, error) { return d.pending.Read(b) }
+
+func (d *statusDevice) Close() error { return nil }
+
+func TestESCPOS_Status(t *testing.T) {
+	// bit 1 and 4 of every reply are always set
+	dev := &statusDevice{replies: []byte{0x12, 0x12, 0x12, 0x12}}
+	p, _ := NewESCPOS(dev)
+
+	status, err := p.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, Status{Online: true}, status)
+	assert.Empty(t, status.Problems())
+	assert.Equal(t, []byte{0x10, 4, 1, 0x10, 4, 2, 0x10, 4, 3, 0x10, 4, 4}, dev.sent.Bytes())
+
+	dev.replies = []byte{0x1A, 0x16 | 0x20, 0x12 | 0x08, 0x12 | 0x0C | 0x60}
+	status, err = p.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, Status{CoverOpen: true, PaperNearEnd: true, PaperOut: true, CutterError: true}, status)
+	assert.Equal(t, []string{"offline", "cover open", "out of paper", "cutter error"}, status.Problems())
+}
+
+// silentDevice never replies
+type silentDevice struct {
+	io.Writer
+	block chan struct{}
+}
+
+func (d silentDevice) Read(b []byte) (int, error) {
+	<-d.block
+	return 0, io.EOF
+}
+
+func (d silentDevice) Close() error { return nil }
+
+func TestESCPOS_StatusNoReply(t *testing.T) {
+	StatusTimeout = 10 * time.Millisecond
+	defer func() { StatusTimeout = 2 * time.Second }()
+
+	dev := silentDevice{Writer: io.Discard, block: make(chan struct{})}
+	defer close(dev.block)
+	p, _ := NewESCPOS(dev)
+	_, err := p.Status()
+	assert.ErrorIs(t, err, errNoReply)
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errAssetNotFound    = errors.New("asset not found")
+	errInvalidAssetName = errors.New("invalid asset name")
+)
+
+var assetNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// AssetMeta describes a stored asset, it is saved next to the image as <name>.json
+type AssetMeta struct {
+	Name       string    `json:"name"`
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	DitherMode string    `json:"dither_mode"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AssetStore keeps named images on disk so receipts can reference them
+// instead of sending the same base64 data with every job.
+// Images are stored already scaled and dithered, ready to print.
+type AssetStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+func NewAssetStore(dir string) *AssetStore {
+	return &AssetStore{dir: dir}
+}
+
+// assetStore is used by Image items that reference an asset by name
+var assetStore = NewAssetStore("assets")
+
+func validateAssetName(name string) error {
+	if !assetNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: %s. Must be 1-64 letters, digits, - or _", errInvalidAssetName, name)
+	}
+	return nil
+}
+
+func (s *AssetStore) path(name, ext string) string {
+	return filepath.Join(s.dir, name+ext)
+}
+
+// Save stores an already processed image under name, replacing any existing asset
+func (s *AssetStore) Save(name string, img image.Image, ditherMode string) (AssetMeta, error) {
+	if err := validateAssetName(name); err != nil {
+		return AssetMeta{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return AssetMeta{}, err
+	}
+	meta := AssetMeta{
+		Name:       name,
+		Width:      img.Bounds().Dx(),
+		Height:     img.Bounds().Dy(),
+		DitherMode: ditherMode,
+		UpdatedAt:  time.Now().UTC(),
+	}
+	metaJSON, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return AssetMeta{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return AssetMeta{}, err
+	}
+	if err := os.WriteFile(s.path(name, ".png"), buf.Bytes(), 0o644); err != nil {
+		return AssetMeta{}, err
+	}
+	if err := os.WriteFile(s.path(name, ".json"), metaJSON, 0o644); err != nil {
+		return AssetMeta{}, err
+	}
+	return meta, nil
+}
+
+// ReadPNG returns the stored PNG bytes of an asset
+func (s *AssetStore) ReadPNG(name string) ([]byte, error) {
+	if err := validateAssetName(name); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, err := os.ReadFile(s.path(name, ".png"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", errAssetNotFound, name)
+	}
+	return data, err
+}
+
+// Load returns the decoded image of an asset
+func (s *AssetStore) Load(name string) (image.Image, error) {
+	data, err := s.ReadPNG(name)
+	if err != nil {
+		return nil, err
+	}
+	return png.Decode(bytes.NewReader(data))
+}
+
+// Delete removes an asset and its metadata
+func (s *AssetStore) Delete(name string) error {
+	if err := validateAssetName(name); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(name, ".png"))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", errAssetNotFound, name)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(name, ".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List returns the metadata of every stored asset sorted by name
+func (s *AssetStore) List() ([]AssetMeta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []AssetMeta{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	list := []AssetMeta{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(s.path(name, ".json"))
+		if err != nil {
+			return nil, err
+		}
+		var meta AssetMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, fmt.Errorf("error reading metadata of asset %s: %v", name, err)
+		}
+		list = append(list, meta)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Name < list[b].Name })
+	return list, nil
+}
+
+func assetErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errAssetNotFound):
+		return 404
+	case errors.Is(err, errInvalidAssetName):
+		return 400
+	}
+	return 500
+}
+
+func handleListAssets(c *gin.Context) {
+	list, err := assetStore.List()
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"assets": list})
+}
+
+// handlePutAsset takes the same fields as an image receipt item,
+// the image is scaled and dithered once here instead of on every print
+func handlePutAsset(c *gin.Context) {
+	name := c.Param("name")
+	if err := validateAssetName(name); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	var img receipt.Image
+	if err := bindItem(c, &img); err != nil {
+		c.JSON(400, errorResponse(err))
+		return
+	}
+	if img.Asset != "" {
+		c.JSON(400, gin.H{"error": "asset upload needs image data"})
+		return
+	}
+
+	meta, err := assetStore.Save(name, img.Bitmap(), string(img.DitherMode))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, meta)
+}
+
+func handleGetAsset(c *gin.Context) {
+	data, err := assetStore.ReadPNG(c.Param("name"))
+	if err != nil {
+		c.JSON(assetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(200, "image/png", data)
+}
+
+func handleDeleteAsset(c *gin.Context) {
+	if err := assetStore.Delete(c.Param("name")); err != nil {
+		c.JSON(assetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"success": true})
+}
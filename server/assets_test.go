@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPNG returns a base64 data URI of a width x height gray gradient
+func testPNG(width, height int) string {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8(x * 255 / width)})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func doRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAssets_UploadAndPrint(t *testing.T) {
+	assetStore = NewAssetStore(t.TempDir())
+	router := setupTestRouter()
+
+	w := doRequest(router, "PUT", "/assets/logo", `{"data": "`+testPNG(800, 100)+`", "dither_mode": "floydsteinberg"}`)
+	assert.Equal(t, 200, w.Code)
+
+	var meta AssetMeta
+	json.Unmarshal(w.Body.Bytes(), &meta)
+	assert.Equal(t, "logo", meta.Name)
+	assert.Equal(t, receipt.PaperWidth, meta.Width, "wide images are scaled down to the paper width")
+	assert.Equal(t, 72, meta.Height)
+
+	w = doRequest(router, "GET", "/assets/logo", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	img, err := png.Decode(w.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, receipt.PaperWidth, img.Bounds().Dx())
+
+	w = doRequest(router, "GET", "/assets", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"logo"`)
+
+	w = doRequest(router, "POST", "/print", `{"receipt": [{"type": "image", "asset": "logo", "alignment": "center"}]}`)
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestAssets_Delete(t *testing.T) {
+	assetStore = NewAssetStore(t.TempDir())
+	router := setupTestRouter()
+
+	w := doRequest(router, "PUT", "/assets/logo", `{"data": "`+testPNG(64, 64)+`", "width": 32}`)
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequest(router, "DELETE", "/assets/logo", "")
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequest(router, "DELETE", "/assets/logo", "")
+	assert.Equal(t, 404, w.Code)
+
+	w = doRequest(router, "POST", "/print", `{"receipt": [{"type": "image", "asset": "logo"}]}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "asset not found")
+}
+
+func TestAssets_UnknownField(t *testing.T) {
+	assetStore = NewAssetStore(t.TempDir())
+	router := setupTestRouter()
+
+	w := doRequest(router, "PUT", "/assets/logo", `{"data": "`+testPNG(8, 8)+`", "dither-mode": "none"}`)
+	assert.Equal(t, 400, w.Code)
+	var body struct {
+		Errors []receipt.FieldError `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, []receipt.FieldError{{Path: "/dither-mode", Message: "unknown field"}}, body.Errors)
+}
+
+func TestAssets_InvalidName(t *testing.T) {
+	assetStore = NewAssetStore(t.TempDir())
+	router := setupTestRouter()
+
+	w := doRequest(router, "PUT", "/assets/bad.name", `{"data": "`+testPNG(8, 8)+`"}`)
+	assert.Equal(t, 400, w.Code)
+
+	w = doRequest(router, "POST", "/print", `{"receipt": [{"type": "image", "asset": "logo", "data": "`+testPNG(8, 8)+`"}]}`)
+	assert.Equal(t, 400, w.Code)
+}
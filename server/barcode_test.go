@@ -0,0 +1,43 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlePrint_InvalidBarcode(t *testing.T) {
+	router := setupTestRouter()
+
+	w := doRequest(router, "POST", "/print", `
+	{
+		"receipt": [
+			{"type": "line", "content": "Product", "font": "A", "alignment": "center", "font_size": 1},
+			{"type": "barcode", "code": "4006381333931", "barcode_type": "EAN13"},
+			{"type": "barcode", "code": "4006381333932", "barcode_type": "EAN13"}
+		]
+	}`)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "/receipt/2/code")
+	assert.Contains(t, w.Body.String(), "check digit should be 1")
+}
+
+func TestHandlePrint_BarcodeTypes(t *testing.T) {
+	router := setupTestRouter()
+
+	w := doRequest(router, "POST", "/print", `
+	{
+		"receipt": [
+			{"type": "barcode", "code": "A40156B", "barcode_type": "CODABAR"},
+			{"type": "barcode", "code": "12345678", "barcode_type": "ITF"},
+			{"type": "barcode", "code": "CODE93", "barcode_type": "CODE93"},
+			{"type": "barcode", "code": "0950110153000", "barcode_type": "GS1_DATABAR"}
+		]
+	}`)
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequest(router, "POST", "/print", `{"receipt": [{"type": "barcode", "code": "1", "barcode_type": "CODE11"}]}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid barcode type: CODE11")
+}
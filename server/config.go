@@ -0,0 +1,314 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/codaea/simpleprint/internal/jsonfield"
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the config file, it describes every printer with a profile
+type Config struct {
+	// Printer is the name of the profile that is used, PRINTER_PROFILE overrides it
+	Printer  string             `json:"printer,omitempty"`
+	Printers map[string]Profile `json:"printers"`
+}
+
+// Profile describes a printer: how to reach it, its paper, fonts and features and the style receipts default to
+type Profile struct {
+	// URI is where the printer is, see render.ParseURI
+	URI   string `json:"uri,omitempty"`
+	Paper Paper  `json:"paper"`
+	// CharsPerLine is the characters per line of each font at size 1, worked out from the paper width if left out
+	CharsPerLine    map[receipt.FontType]int `json:"chars_per_line,omitempty"`
+	Features        render.Features          `json:"features"`
+	DefaultCodePage receipt.CodePage         `json:"default_code_page,omitempty"`
+	DefaultStyle    receipt.TextStyle        `json:"default_style"`
+	// CutFeedLines is the lines fed before a cut, the printer's default if nil
+	CutFeedLines *int `json:"cut_feed_lines,omitempty"`
+}
+
+// Paper is the printable width of the paper. WidthDots is worked out from WidthMM and DPI if it is left out.
+type Paper struct {
+	WidthMM   float64 `json:"width_mm,omitempty"`
+	WidthDots int     `json:"width_dots,omitempty"`
+	DPI       int     `json:"dpi,omitempty"`
+}
+
+// minCharWidth is narrower than the characters of any printer font, in dots
+const minCharWidth = 6
+
+// configFiles are the config files looked for in the working directory when CONFIG_FILE isn't set
+var configFiles = []string{"simpleprint.yaml", "simpleprint.yml", "simpleprint.toml", "simpleprint.json"}
+
+// profileName and profile are the profile in use, set by Configure
+var (
+	profileName = "default"
+	profile     = defaultProfile()
+)
+
+func defaultProfile() Profile {
+	return Profile{Features: render.AllFeatures}
+}
+
+// ActiveProfile returns the name and profile of the printer the server prints on
+func ActiveProfile() (string, Profile) {
+	return profileName, profile
+}
+
+// UnmarshalJSON starts from a printer that has every feature, so a profile only lists what its printer lacks
+func (p *Profile) UnmarshalJSON(data []byte) error {
+	type alias Profile
+	aux := alias(defaultProfile())
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*p = Profile(aux)
+	return nil
+}
+
+// LoadConfig reads a config file, in YAML or TOML by its extension and JSON otherwise
+func LoadConfig(path string) (Config, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var data any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(body, &data)
+	case ".toml":
+		err = toml.Unmarshal(body, &data)
+	default:
+		err = json.Unmarshal(body, &data)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if errs := unknownKeys("", data, reflect.TypeOf(Config{})); len(errs) > 0 {
+		return Config{}, fmt.Errorf("%s: %w", path, errors.Join(errs...))
+	}
+
+	// YAML and TOML are decoded through JSON, so the checks in the UnmarshalJSON methods apply to them too
+	converted, err := json.Marshal(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(converted, &cfg); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// unknownKeys returns an error for every key of data that isn't a field of t, like a misspelled setting,
+// with the path of the key
+func unknownKeys(path string, data any, t reflect.Type) []error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	object, ok := data.(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	var errs []error
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		switch t.Kind() {
+		case reflect.Map:
+			errs = append(errs, unknownKeys(keyPath, object[key], t.Elem())...)
+		case reflect.Struct:
+			field, ok := jsonfield.Fields(t)[key]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown field", keyPath))
+				continue
+			}
+			errs = append(errs, unknownKeys(keyPath, object[key], field.Type)...)
+		}
+	}
+	return errs
+}
+
+// Profile returns the profile called name, or the only one if name is empty
+func (c Config) Profile(name string) (string, Profile, error) {
+	names := make([]string, 0, len(c.Printers))
+	for n := range c.Printers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	if name == "" {
+		switch len(names) {
+		case 0:
+			return "", Profile{}, errors.New("the config file has no printers")
+		case 1:
+			name = names[0]
+		default:
+			return "", Profile{}, fmt.Errorf("the config file has several printers, choose one of %s with printer or PRINTER_PROFILE", strings.Join(names, ", "))
+		}
+	}
+	p, ok := c.Printers[name]
+	if !ok {
+		return "", Profile{}, fmt.Errorf("no printer called %s in the config file, it has %s", name, strings.Join(names, ", "))
+	}
+	return name, p, nil
+}
+
+// overrideFromEnv replaces the settings of p that are set in the environment:
+// PRINTER_PATH, PAPER_WIDTH and CUT_FEED_LINES
+func (p *Profile) overrideFromEnv() error {
+	var errs []error
+	if uri, found := os.LookupEnv("PRINTER_PATH"); found && uri != "" {
+		p.URI = uri
+	}
+	if s := os.Getenv("PAPER_WIDTH"); s != "" {
+		width, err := strconv.Atoi(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("PAPER_WIDTH: %s is not a number", s))
+		}
+		// the width in mm of the file is for the width it replaces
+		p.Paper.WidthDots, p.Paper.WidthMM = width, 0
+	}
+	if s := os.Getenv("CUT_FEED_LINES"); s != "" {
+		lines, err := strconv.Atoi(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("CUT_FEED_LINES: %s is not a number", s))
+		}
+		p.CutFeedLines = &lines
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the profile and fills in the paper width in dots when it is left out,
+// it returns every problem it finds
+func (p *Profile) Validate() error {
+	var errs []error
+	add := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf(field+": "+format, args...))
+	}
+
+	if _, _, err := render.ParseURI(p.URI); err != nil {
+		add("uri", "%v", err)
+	}
+
+	paper := &p.Paper
+	if paper.WidthMM < 0 {
+		add("paper.width_mm", "must be more than 0")
+	}
+	if paper.DPI < 0 {
+		add("paper.dpi", "must be more than 0")
+	}
+	if paper.WidthDots < 0 {
+		add("paper.width_dots", "must be more than 0")
+	}
+	switch {
+	case paper.WidthDots == 0 && paper.WidthMM > 0 && paper.DPI > 0:
+		// printers take the width in bytes of 8 dots
+		paper.WidthDots = int(paper.WidthMM*float64(paper.DPI)/25.4) / 8 * 8
+	case paper.WidthDots == 0:
+		paper.WidthDots = 576
+	case paper.WidthMM > 0 && paper.DPI > 0:
+		// a dot either way is rounding, printers make the width a multiple of 8
+		mm := float64(paper.WidthDots) * 25.4 / float64(paper.DPI)
+		if math.Abs(mm-paper.WidthMM)*float64(paper.DPI)/25.4 > 8 {
+			add("paper.width_dots", "%d dots are %.1fmm at %d dpi, not %gmm. width_mm is the printable width, not the width of the paper", paper.WidthDots, mm, paper.DPI, paper.WidthMM)
+		}
+	}
+
+	for font, n := range p.CharsPerLine {
+		switch {
+		case font != receipt.FontA && font != receipt.FontB && font != receipt.FontC:
+			add("chars_per_line", "invalid font: %s. Must be A, B, or C", font)
+		case n < 1 || n > paper.WidthDots/minCharWidth:
+			add("chars_per_line."+string(font), "invalid characters per line: %d. Must be 1-%d", n, paper.WidthDots/minCharWidth)
+		}
+	}
+
+	if p.DefaultCodePage != "" && len(p.Features.CodePages) > 0 && !slices.Contains(p.Features.CodePages, p.DefaultCodePage) {
+		add("default_code_page", "%s is not one of the code pages in features.code_pages", p.DefaultCodePage)
+	}
+	if size := p.DefaultStyle.FontSize; size < 0 || size > receipt.MaxFontSize {
+		add("default_style.font_size", "invalid font_size: %d. Must be 1-%d", size, receipt.MaxFontSize)
+	}
+	if p.CutFeedLines != nil && (*p.CutFeedLines < 0 || *p.CutFeedLines > 255) {
+		add("cut_feed_lines", "invalid cut_feed_lines: %d. Must be 0-255", *p.CutFeedLines)
+	}
+	return errors.Join(errs...)
+}
+
+// loadProfile loads the profile from the config file at CONFIG_FILE or one of configFiles,
+// and a profile that only has the defaults if there is no config file
+func loadProfile() (string, Profile, error) {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		for _, name := range configFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+	}
+	if path == "" {
+		return "default", defaultProfile(), nil
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return "", Profile{}, err
+	}
+	name := cfg.Printer
+	if env := os.Getenv("PRINTER_PROFILE"); env != "" {
+		name = env
+	}
+	return cfg.Profile(name)
+}
+
+// applyProfile sets up the receipt and render packages for the printer of p
+func applyProfile(p Profile) {
+	receipt.PaperWidth = p.Paper.WidthDots
+	receipt.FontColumns = p.CharsPerLine
+	if receipt.FontColumns == nil {
+		receipt.FontColumns = map[receipt.FontType]int{}
+	}
+	receipt.DefaultStyle = p.DefaultStyle
+	receipt.DefaultCodePage = p.DefaultCodePage
+	if p.CutFeedLines != nil {
+		render.CutFeedLines = *p.CutFeedLines
+	}
+}
+
+// OpenPrinter opens the printer at uri with the features of the profile, and resets it
+func OpenPrinter(uri string) (*render.ESCPOS, error) {
+	p, err := render.OpenURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	p.Features = profile.Features
+	p.Init()
+	p.Smooth(true)
+	return p, nil
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/stretchr/testify/assert"
+)
+
+const yamlConfig = `
+printer: kitchen
+printers:
+  front:
+    uri: usb:///dev/usb/lp0
+    paper: {width_mm: 72, width_dots: 576, dpi: 203}
+  kitchen:
+    uri: tcp://192.168.1.50
+    paper: {width_mm: 48, dpi: 203}
+    chars_per_line: {A: 32, B: 42}
+    features:
+      cutter: false
+      code_pages: [PC437, PC858]
+    default_code_page: PC858
+    default_style: {font: B, font_size: 1, alignment: center}
+    cut_feed_lines: 4
+`
+
+const tomlConfig = `
+[printers.front]
+uri = "/dev/usb/lp0"
+
+[printers.front.paper]
+width_dots = 576
+width_mm = 72
+dpi = 203
+
+[printers.front.features]
+drawer = false
+`
+
+func writeConfig(t *testing.T, name, body string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// restoreSettings puts back the settings Configure changes once the test is done
+func restoreSettings(t *testing.T) {
+	width, columns, style, codePage, cutFeed := receipt.PaperWidth, receipt.FontColumns, receipt.DefaultStyle, receipt.DefaultCodePage, render.CutFeedLines
+	name, p := profileName, profile
+	t.Cleanup(func() {
+		receipt.PaperWidth, receipt.FontColumns, receipt.DefaultStyle, receipt.DefaultCodePage, render.CutFeedLines = width, columns, style, codePage, cutFeed
+		profileName, profile = name, p
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, "simpleprint.yaml", yamlConfig))
+	assert.NoError(t, err)
+
+	name, p, err := cfg.Profile(cfg.Printer)
+	assert.NoError(t, err)
+	assert.Equal(t, "kitchen", name)
+	assert.Equal(t, render.Features{Cutter: false, Drawer: true, QR: true, CodePages: []receipt.CodePage{"PC437", "PC858"}}, p.Features)
+	assert.Equal(t, receipt.TextStyle{Font: receipt.FontB, FontSize: 1, Alignment: receipt.AlignCenter}, p.DefaultStyle)
+	assert.NoError(t, p.Validate())
+	// 48mm at 203 dpi is 383 dots, rounded down to a multiple of 8
+	assert.Equal(t, 376, p.Paper.WidthDots)
+
+	_, front, err := cfg.Profile("front")
+	assert.NoError(t, err)
+	assert.Equal(t, render.AllFeatures, front.Features)
+
+	_, _, err = cfg.Profile("")
+	assert.ErrorContains(t, err, "front, kitchen")
+	_, _, err = cfg.Profile("bar")
+	assert.ErrorContains(t, err, "no printer called bar")
+
+	cfg, err = LoadConfig(writeConfig(t, "simpleprint.toml", tomlConfig))
+	assert.NoError(t, err)
+	name, p, err = cfg.Profile("")
+	assert.NoError(t, err)
+	assert.Equal(t, "front", name)
+	assert.False(t, p.Features.Drawer)
+	assert.NoError(t, p.Validate())
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	_, err := LoadConfig(writeConfig(t, "bad.yaml", "printers:\n  a:\n    default_style: {font: D}\n"))
+	assert.ErrorContains(t, err, "invalid font: D")
+
+	// misspelled keys are reported with their path instead of being left at their default
+	_, err = LoadConfig(writeConfig(t, "typo.yaml", "printers:\n  a:\n    featuers: {cutter: false}\n    paper: {widht_dots: 999}\n    default_style: {fontsize: 3}\n"))
+	assert.ErrorContains(t, err, "printers.a.featuers: unknown field")
+	assert.ErrorContains(t, err, "printers.a.paper.widht_dots: unknown field")
+	assert.ErrorContains(t, err, "printers.a.default_style.fontsize: unknown field")
+
+	_, err = LoadConfig(writeConfig(t, "typo.json", `{"printer": "a", "printers": {"a": {}}, "cut_feed_lines": 3}`))
+	assert.ErrorContains(t, err, "cut_feed_lines: unknown field")
+
+	_, err = LoadConfig(writeConfig(t, "bad.toml", "[printers.a.features]\ncode_pages = [\"PC999\"]\n"))
+	assert.ErrorContains(t, err, "invalid code_page: PC999")
+}
+
+func TestProfile_Validate(t *testing.T) {
+	lines := 300
+	p := Profile{
+		URI:             "bluetooth://printer",
+		Paper:           Paper{WidthMM: 80, WidthDots: 576, DPI: 203},
+		CharsPerLine:    map[receipt.FontType]int{"D": 10, receipt.FontA: 0},
+		Features:        render.Features{CodePages: []receipt.CodePage{receipt.CodePagePC437}},
+		DefaultCodePage: receipt.CodePagePC866,
+		DefaultStyle:    receipt.TextStyle{FontSize: 9},
+		CutFeedLines:    &lines,
+	}
+	err := p.Validate()
+	for _, field := range []string{"uri:", "paper.width_dots:", "chars_per_line:", "chars_per_line.A:", "default_code_page:", "default_style.font_size:", "cut_feed_lines:"} {
+		assert.ErrorContains(t, err, field)
+	}
+
+	p = Profile{}
+	assert.NoError(t, p.Validate())
+	assert.Equal(t, 576, p.Paper.WidthDots)
+
+	// 72mm at 203 dpi is 575 dots, rounded down to a multiple of 8
+	p = Profile{Paper: Paper{WidthMM: 72, DPI: 203}, CharsPerLine: map[receipt.FontType]int{receipt.FontA: 94, receipt.FontB: 95}}
+	err = p.Validate()
+	assert.Equal(t, 568, p.Paper.WidthDots)
+	assert.ErrorContains(t, err, "chars_per_line.B: invalid characters per line: 95. Must be 1-94")
+	assert.NotContains(t, err.Error(), "chars_per_line.A")
+}
+
+func TestConfigure_ConfigFile(t *testing.T) {
+	restoreSettings(t)
+	t.Setenv("CONFIG_FILE", writeConfig(t, "simpleprint.yaml", yamlConfig))
+	t.Setenv("PRINTER_PATH", "")
+	t.Setenv("PAPER_WIDTH", "")
+	t.Setenv("CUT_FEED_LINES", "")
+
+	assert.NoError(t, Configure())
+	name, p := ActiveProfile()
+	assert.Equal(t, "kitchen", name)
+	assert.Equal(t, "tcp://192.168.1.50", p.URI)
+	assert.Equal(t, 376, receipt.PaperWidth)
+	assert.Equal(t, 42, receipt.CharsPerLine(receipt.FontB, 1))
+	assert.Equal(t, receipt.CodePagePC858, receipt.DefaultCodePage)
+	assert.Equal(t, 4, render.CutFeedLines)
+
+	// the environment overrides the profile
+	t.Setenv("PRINTER_PROFILE", "front")
+	t.Setenv("PRINTER_PATH", "/dev/usb/lp3")
+	t.Setenv("PAPER_WIDTH", "512")
+	assert.NoError(t, Configure())
+	name, p = ActiveProfile()
+	assert.Equal(t, "front", name)
+	assert.Equal(t, "/dev/usb/lp3", p.URI)
+	assert.Equal(t, 512, receipt.PaperWidth)
+
+	// every problem is reported at startup
+	t.Setenv("PAPER_WIDTH", "wide")
+	t.Setenv("PRINTER_PATH", "bluetooth://printer")
+	err := Configure()
+	assert.ErrorContains(t, err, "PAPER_WIDTH: wide is not a number")
+	assert.ErrorContains(t, err, "uri:")
+}
+
+func TestConfigure_NoConfigFile(t *testing.T) {
+	restoreSettings(t)
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("PRINTER_PATH", "/dev/usb/lp0")
+	t.Setenv("PAPER_WIDTH", "")
+	t.Setenv("CUT_FEED_LINES", "")
+
+	assert.NoError(t, Configure())
+	name, p := ActiveProfile()
+	assert.Equal(t, "default", name)
+	assert.Equal(t, "/dev/usb/lp0", p.URI)
+	assert.Equal(t, render.AllFeatures, p.Features)
+	assert.Equal(t, 576, receipt.PaperWidth)
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/gin-gonic/gin"
+)
+
+// handleOpenDrawer kicks the cash drawer without printing anything,
+// the body is optional and takes the same fields as a drawer item
+func handleOpenDrawer(c *gin.Context) {
+	var d receipt.Drawer
+	if err := bindItem(c, &d); err != nil {
+		c.JSON(400, errorResponse(err))
+		return
+	}
+
+	if !tryLockPrinter(c) {
+		return
+	}
+	defer printerMutex.Unlock()
+
+	p := c.MustGet("printer").(*render.ESCPOS)
+	if err := p.OpenDrawer(d); errors.Is(err, render.ErrUnsupported) {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"success": true})
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleOpenDrawer(t *testing.T) {
+	router := setupTestRouter()
+
+	w := doRequest(router, "POST", "/drawer/open", "")
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequest(router, "POST", "/drawer/open", `{"pin": 5}`)
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequest(router, "POST", "/drawer/open", `{"pin": 4}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), `"path":"/pin"`)
+
+	w = doRequest(router, "POST", "/drawer/open", `{"pin": 5, "on": 100}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), `"path":"/on"`)
+
+	printerMutex.Lock()
+	w = doRequest(router, "POST", "/drawer/open", "")
+	printerMutex.Unlock()
+	assert.Equal(t, 503, w.Code)
+}
+
+func TestHandlePrint_DrawerAndBeep(t *testing.T) {
+	router := setupTestRouter()
+
+	w := doRequest(router, "POST", "/print", `
+	{
+		"receipt": [
+			{"type": "line", "content": "Cash sale", "font": "A", "alignment": "left", "font_size": 1},
+			{"type": "drawer", "pin": 2},
+			{"type": "beep", "count": 2}
+		]
+	}`)
+	assert.Equal(t, 200, w.Code)
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/boombuler/barcode"
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/gin-gonic/gin"
+)
+
+// layoutCheck collects the problems found while laying out a receipt, each reported once
+type layoutCheck struct {
+	errors   []receipt.FieldError
+	warnings []receipt.FieldError
+	seen     map[receipt.FieldError]bool
+}
+
+func (l *layoutCheck) add(list *[]receipt.FieldError, path, format string, args ...any) {
+	fe := receipt.FieldError{Path: path, Message: fmt.Sprintf(format, args...)}
+	if !l.seen[fe] {
+		l.seen[fe] = true
+		*list = append(*list, fe)
+	}
+}
+
+func (l *layoutCheck) errorf(path, format string, args ...any) {
+	l.add(&l.errors, path, format, args...)
+}
+
+func (l *layoutCheck) warnf(path, format string, args ...any) {
+	l.add(&l.warnings, path, format, args...)
+}
+
+var unknownVariable = regexp.MustCompile(`\{\{[A-Za-z0-9_]+\}\}`)
+
+// checkLayout lays out every copy of a decoded request the way the printer would,
+// finding text that wraps, cells that are cut off and codes or images that don't fit the paper
+func checkLayout(req receipt.Request) ([]receipt.FieldError, []receipt.FieldError) {
+	l := &layoutCheck{seen: make(map[receipt.FieldError]bool)}
+
+	for n := 0; n < max(1, req.Copies); n++ {
+		vars := req.VariablesFor(n)
+		// dots used on the current line, text items continue the line of the one before
+		lineDots := 0
+		for i, item := range req.Receipt {
+			path := "/receipt/" + strconv.Itoa(i)
+			item = receipt.WithVariables(item, vars)
+			if _, ok := item.(receipt.Text); !ok {
+				lineDots = 0
+			}
+
+			switch v := item.(type) {
+			case receipt.Line:
+				l.checkVariables(path+"/content", v.Content)
+				if chars, fit := utf8.RuneCountInString(v.Content), receipt.CharsPerLine(v.Font, v.FontSize); chars > fit {
+					l.warnf(path+"/content", "content is %d characters, only %d fit on a line so it wraps", chars, fit)
+				}
+			case receipt.Text:
+				l.checkVariables(path+"/content", v.Content)
+				for j, part := range strings.Split(v.Content, "\n") {
+					if j > 0 {
+						lineDots = 0
+					}
+					lineDots += utf8.RuneCountInString(part) * receipt.CharWidth(v.Font, v.FontSize)
+					if lineDots > receipt.PaperWidth {
+						l.warnf(path+"/content", "text runs past the paper width of %d dots so it wraps", receipt.PaperWidth)
+					}
+				}
+			case receipt.Columns:
+				for j, col := range v.Columns {
+					l.checkVariables(fmt.Sprintf("%s/columns/%d/content", path, j), col.Content)
+				}
+				widths := v.Widths()
+				for _, j := range v.Truncated() {
+					l.warnf(fmt.Sprintf("%s/columns/%d/content", path, j), "content is cut off to %d characters", widths[j])
+				}
+			case receipt.Image:
+				// assets are stored at the size they are printed
+				if v.Asset == "" && v.Img != nil {
+					width := v.Width
+					if width == 0 {
+						width = v.Img.Bounds().Dx()
+					}
+					if width > receipt.PaperWidth {
+						l.warnf(path, "image is %d dots wide, it is scaled down to the paper width of %d", width, receipt.PaperWidth)
+					}
+				}
+			case receipt.Barcode:
+				if modules, ok := v.Modules(); ok && modules*v.ModuleWidth > receipt.PaperWidth {
+					l.errorf(path+"/module_width", "barcode is %d dots wide, more than the paper width of %d. Use a smaller module_width or a shorter code", modules*v.ModuleWidth, receipt.PaperWidth)
+				}
+			case receipt.QRCode:
+				// printers without QR support print them as images
+				render := v.Render
+				if !profile.Features.QR {
+					render = receipt.RenderRaster
+				}
+				l.checkCode(path, render, v.Size, v.Encode)
+			case receipt.PDF417:
+				if v.Render == receipt.RenderNative && v.Columns > 0 {
+					// 17 modules for each data column and 69 for the start and stop patterns and row indicators
+					l.checkWidth(path, (17*v.Columns+69)*v.Size)
+				} else {
+					l.checkCode(path, v.Render, v.Size, v.Encode)
+				}
+			case receipt.DataMatrix:
+				l.checkCode(path, v.Render, v.Size, v.Encode)
+			case receipt.Aztec:
+				l.checkCode(path, v.Render, v.Size, v.Encode)
+			}
+		}
+	}
+	return l.errors, l.warnings
+}
+
+// checkVariables warns about {{name}} placeholders no variable replaced
+func (l *layoutCheck) checkVariables(path, s string) {
+	for _, name := range unknownVariable.FindAllString(s, -1) {
+		l.warnf(path, "%s is not a variable, it is printed as it is", name)
+	}
+}
+
+// checkCode draws a 2D code in Go to find its width. A code printed as an image fails to print
+// if it is wider than the paper, and the printer leaves out one it draws itself.
+// The printer may choose a different version than Go for a native code, so its width is close, not exact.
+func (l *layoutCheck) checkCode(path string, render receipt.RenderMode, size int, encode func() (barcode.Barcode, error)) {
+	code, err := encode()
+	if err != nil {
+		if render == receipt.RenderRaster {
+			l.errorf(path+"/code", "%v", err)
+		}
+		return
+	}
+	l.checkWidth(path, code.Bounds().Dx()*size)
+}
+
+// checkWidth reports a 2D code that is width dots wide if it doesn't fit on the paper
+func (l *layoutCheck) checkWidth(path string, width int) {
+	if width > receipt.PaperWidth {
+		l.errorf(path+"/size", "code is %d dots wide, more than the paper width of %d", width, receipt.PaperWidth)
+	}
+}
+
+// dryRun is the result of checking a request, err is the error decoding it
+func dryRun(req receipt.Request, err error) receipt.ValidationResult {
+	result := receipt.ValidationResult{Errors: []receipt.FieldError{}, Warnings: []receipt.FieldError{}}
+	if err != nil {
+		var verr *receipt.ValidationError
+		if errors.As(err, &verr) {
+			result.Errors = verr.Errors
+		} else {
+			result.Errors = []receipt.FieldError{{Message: err.Error()}}
+		}
+		return result
+	}
+
+	errs, warnings := checkLayout(req)
+	result.Errors = append(result.Errors, errs...)
+	result.Warnings = append(result.Warnings, warnings...)
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+// handleValidate checks a print request like /print does, and lays it out, but doesn't print it.
+// It never touches the printer, so it works while a job is printing.
+func handleValidate(c *gin.Context) {
+	c.JSON(200, dryRun(bindPrintRequest(c)))
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/stretchr/testify/assert"
+)
+
+func validateRequest(t *testing.T, contentType, body string) receipt.ValidationResult {
+	w := doRequestWithType(setupTestRouter(), "/validate", contentType, body)
+	assert.Equal(t, 200, w.Code)
+	var result receipt.ValidationResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	return result
+}
+
+func TestHandleValidate_Warnings(t *testing.T) {
+	result := validateRequest(t, "application/json", `{"variables": {"name": "Ann"}, "receipt": [
+		{"type": "line", "content": "Hello {{name}}", "font_size": 2},
+		{"type": "line", "content": "Thank you for shopping with us", "font_size": 2},
+		{"type": "columns", "columns": [{"content": "A very long product name", "width": 10}, {"content": "$1.00"}]},
+		{"type": "text", "content": "`+strings.Repeat("a", 30)+`"},
+		{"type": "text", "content": "`+strings.Repeat("b", 30)+`\n"},
+		{"type": "line", "content": "Dear {{customer}}"},
+		{"type": "image", "data": "`+testPNG(800, 10)+`"}
+	]}`)
+
+	assert.True(t, result.Valid)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, []receipt.FieldError{
+		{Path: "/receipt/1/content", Message: "content is 30 characters, only 24 fit on a line so it wraps"},
+		{Path: "/receipt/2/columns/0/content", Message: "content is cut off to 10 characters"},
+		{Path: "/receipt/4/content", Message: "text runs past the paper width of 576 dots so it wraps"},
+		{Path: "/receipt/5/content", Message: "{{customer}} is not a variable, it is printed as it is"},
+		{Path: "/receipt/6", Message: "image is 800 dots wide, it is scaled down to the paper width of 576"},
+	}, result.Warnings)
+}
+
+func TestHandleValidate_WarningsOncePerCopy(t *testing.T) {
+	result := validateRequest(t, "application/json", `{"copy_variables": [{"copy": "CUSTOMER"}, {"copy": "MERCHANT COPY, KEEP THIS ONE FOR THE MONTHLY RECORDS"}], "receipt": [
+		{"type": "line", "content": "{{copy}} {{missing}}"}
+	]}`)
+
+	assert.True(t, result.Valid)
+	assert.Equal(t, []receipt.FieldError{
+		{Path: "/receipt/0/content", Message: "{{missing}} is not a variable, it is printed as it is"},
+		{Path: "/receipt/0/content", Message: "content is 64 characters, only 48 fit on a line so it wraps"},
+	}, result.Warnings)
+}
+
+func TestHandleValidate_Errors(t *testing.T) {
+	result := validateRequest(t, "application/json", `{"receipt": [
+		{"type": "line", "content": "ok", "font_size": 10},
+		{"type": "barcode", "code": "12345", "barcode_type": "EAN13"}
+	]}`)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []string{"/receipt/0/font_size", "/receipt/1/code"}, []string{result.Errors[0].Path, result.Errors[1].Path})
+	assert.Empty(t, result.Warnings)
+
+	result = validateRequest(t, "application/json", `{"receipt": [`)
+	assert.False(t, result.Valid)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, "", result.Errors[0].Path)
+}
+
+func TestHandleValidate_RasterCodeTooWide(t *testing.T) {
+	result := validateRequest(t, "application/json", `{"receipt": [
+		{"type": "qr", "code": "`+strings.Repeat("https://example.com/", 10)+`", "size": 16, "render": "raster"},
+		{"type": "qr", "code": "small", "size": 4, "render": "raster"}
+	]}`)
+	assert.False(t, result.Valid)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, "/receipt/0/size", result.Errors[0].Path)
+	assert.Contains(t, result.Errors[0].Message, "more than the paper width of 576")
+}
+
+func TestHandleValidate_CodeTooWide(t *testing.T) {
+	result := validateRequest(t, "application/json", `{"receipt": [
+		{"type": "barcode", "code": "ABCDEFGHIJKLMNOPQRSTUVWXYZ", "barcode_type": "CODE128", "module_width": 6},
+		{"type": "barcode", "code": "ABCDEFGHIJKLMNOPQRSTUVWXYZ", "barcode_type": "CODE128", "module_width": 1},
+		{"type": "qr", "code": "`+strings.Repeat("https://example.com/", 10)+`", "size": 16},
+		{"type": "pdf417", "code": "M1DOE/JOHN", "columns": 10, "size": 4},
+		{"type": "datamatrix", "code": "small"}
+	]}`)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []string{"/receipt/0/module_width", "/receipt/2/size", "/receipt/3/size"},
+		[]string{result.Errors[0].Path, result.Errors[1].Path, result.Errors[2].Path})
+	assert.Len(t, result.Errors, 3)
+	assert.Contains(t, result.Errors[0].Message, "barcode is 1926 dots wide")
+	assert.Contains(t, result.Errors[2].Message, "code is 956 dots wide")
+}
+
+func TestHandleValidate_YAMLLines(t *testing.T) {
+	result := validateRequest(t, "application/yaml", "receipt:\n  - type: line\n    content: ok\n  - type: feed\n    lines: 999\n")
+	assert.False(t, result.Valid)
+	assert.Equal(t, []receipt.FieldError{{Path: "/receipt/1/lines", Message: "invalid lines: 999. Must be 0-255", Line: 4}}, result.Errors)
+}
+
+func TestHandleValidate_WhilePrinterIsBusy(t *testing.T) {
+	printerMutex.Lock()
+	defer printerMutex.Unlock()
+
+	result := validateRequest(t, "application/json", `{"receipt": [{"type": "line", "content": "still works"}]}`)
+	assert.True(t, result.Valid)
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/gin-gonic/gin"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// bindPrintRequest decodes the print request in the body. Besides JSON, YAML and TOML bodies are
+// accepted by their content type. They are converted to JSON so they decode exactly like a JSON request,
+// and errors in a receipt item point at the line the item starts on.
+func bindPrintRequest(c *gin.Context) (receipt.Request, error) {
+	var req receipt.Request
+	contentType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+
+	switch contentType {
+	case "application/yaml", "application/x-yaml", "text/yaml":
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return req, err
+		}
+		return decodeYAMLRequest(body)
+	case "application/toml", "text/toml":
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return req, err
+		}
+		return decodeTOMLRequest(body)
+	}
+
+	err := c.ShouldBindJSON(&req)
+	return req, err
+}
+
+// bindItem decodes the JSON body into the item v points to like an item of /print,
+// an empty body is an item that leaves out every field
+func bindItem(c *gin.Context, v any) error {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		body = []byte("{}")
+	}
+	return receipt.DecodeItem(body, v)
+}
+
+// DecodeFile decodes a print request read from a file, the format comes from the extension of name
+// and is JSON unless it is .yaml, .yml or .toml
+func DecodeFile(name string, body []byte) (receipt.Request, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return decodeYAMLRequest(body)
+	case ".toml":
+		return decodeTOMLRequest(body)
+	}
+	var req receipt.Request
+	err := json.Unmarshal(body, &req)
+	return req, err
+}
+
+func decodeYAMLRequest(body []byte) (receipt.Request, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return receipt.Request{}, err
+	}
+	var data any
+	if err := doc.Decode(&data); err != nil {
+		return receipt.Request{}, err
+	}
+
+	// the line of every receipt item, to report errors in them
+	var lines []int
+	if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		root := doc.Content[0]
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == "receipt" {
+				for _, item := range root.Content[i+1].Content {
+					lines = append(lines, item.Line)
+				}
+			}
+		}
+	}
+	return decodeConvertedRequest(data, lines)
+}
+
+var tomlReceiptHeader = regexp.MustCompile(`^\s*\[\[\s*"?receipt"?\s*\]\]`)
+
+func decodeTOMLRequest(body []byte) (receipt.Request, error) {
+	var data map[string]any
+	if err := toml.Unmarshal(body, &data); err != nil {
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			row, _ := decodeErr.Position()
+			return receipt.Request{}, fmt.Errorf("line %d: %v", row, err)
+		}
+		return receipt.Request{}, err
+	}
+
+	// receipt items are written as [[receipt]] tables, the nth header starts item n
+	var lines []int
+	for i, line := range strings.Split(string(body), "\n") {
+		if tomlReceiptHeader.MatchString(line) {
+			lines = append(lines, i+1)
+		}
+	}
+	return decodeConvertedRequest(data, lines)
+}
+
+// decodeConvertedRequest decodes a YAML or TOML document through JSON, lines are where each receipt item starts
+func decodeConvertedRequest(data any, lines []int) (receipt.Request, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return receipt.Request{}, err
+	}
+
+	var req receipt.Request
+	err = json.Unmarshal(body, &req)
+	var verr *receipt.ValidationError
+	if errors.As(err, &verr) {
+		for i, fe := range verr.Errors {
+			if n, ok := receiptIndex(fe.Path); ok && n < len(lines) {
+				verr.Errors[i].Line = lines[n]
+			}
+		}
+	}
+	return req, err
+}
+
+// receiptIndex returns the index of the receipt item a JSON pointer is in
+func receiptIndex(path string) (int, bool) {
+	rest, ok := strings.CutPrefix(path, "/receipt/")
+	if !ok {
+		return 0, false
+	}
+	index, _, _ := strings.Cut(rest, "/")
+	n, err := strconv.Atoi(index)
+	return n, err == nil
+}
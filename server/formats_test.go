@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func doRequestWithType(router *gin.Engine, path, contentType, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+const yamlReceipt = `# daily specials
+cut: partial
+receipt:
+  - type: line
+    content: Specials
+    font_size: 2
+    font: A
+    alignment: center
+  - type: barcode
+    code: "123456789012"
+    barcode_type: UPCA
+`
+
+func TestDecodeYAMLRequest(t *testing.T) {
+	req, err := decodeYAMLRequest([]byte(yamlReceipt))
+	assert.NoError(t, err)
+	assert.Equal(t, receipt.CutPartial, req.Cut)
+	assert.Equal(t, "Specials", req.Receipt[0].(receipt.Line).Content)
+	assert.Equal(t, receipt.BarcodeUPCA, req.Receipt[1].(receipt.Barcode).BarcodeType)
+
+	_, err = decodeYAMLRequest([]byte("receipt:\n  - type: line\n    content: ok\n  - type: line\n    alignment: middle\n"))
+	assert.ErrorContains(t, err, "line 4: ")
+	assert.ErrorContains(t, err, "invalid alignment")
+
+	_, err = decodeYAMLRequest([]byte("receipt:\n  - type: line\n    content: \"unclosed\n"))
+	assert.ErrorContains(t, err, "line 3")
+}
+
+const tomlReceipt = `copies = 2
+
+[[receipt]]
+type = "line"
+content = "Specials"
+font_size = 2
+
+[[receipt]]
+type = "feed"
+lines = 2
+`
+
+func TestDecodeTOMLRequest(t *testing.T) {
+	req, err := decodeTOMLRequest([]byte(tomlReceipt))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, req.Copies)
+	assert.Equal(t, 2, req.Receipt[0].(receipt.Line).FontSize)
+	assert.Equal(t, receipt.Feed{Type: "feed", Lines: 2}, req.Receipt[1])
+
+	_, err = decodeTOMLRequest([]byte(tomlReceipt + "\n[[receipt]]\ntype = \"qr\"\ncode = \"\"\n"))
+	assert.ErrorContains(t, err, "line 12: ")
+	assert.ErrorContains(t, err, "code is empty")
+
+	_, err = decodeTOMLRequest([]byte("copies = = 2"))
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestHandlePrint_YAMLAndTOML(t *testing.T) {
+	router := setupTestRouter()
+
+	w := doRequestWithType(router, "/print", "application/yaml", yamlReceipt)
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequestWithType(router, "/print", "application/toml; charset=utf-8", tomlReceipt)
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequestWithType(router, "/print", "application/yaml", "receipt:\n  - type: cut\n    mode: sideways\n")
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "line 2")
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/gin-gonic/gin"
+)
+
+// Global mutex to serialize printer access
+var printerMutex sync.Mutex
+
+// tryLockPrinter locks the printer, or responds busy and returns false if it is already in use
+func tryLockPrinter(c *gin.Context) bool {
+	if !printerMutex.TryLock() {
+		c.JSON(503, gin.H{
+			"error":   "Printer is busy",
+			"message": "Another print job is currently in progress. Please try again later.",
+		})
+		return false
+	}
+	return true
+}
+
+func handlePrint(c *gin.Context) {
+	// Try to lock the printer, return busy if already in use
+	if !tryLockPrinter(c) {
+		return
+	}
+	defer printerMutex.Unlock()
+
+	p := c.MustGet("printer").(*render.ESCPOS) // get printer object from middleware
+
+	req, err := bindPrintRequest(c)
+	if err != nil {
+		c.JSON(400, errorResponse(err))
+		return
+	}
+
+	if err := Print(p, req); err != nil {
+		c.JSON(printErrorStatus(err), errorResponse(err))
+		return
+	}
+	c.JSON(200, gin.H{"success": true})
+}
+
+// printErrorStatus is the status of a failed print: 400 when the request asks for something
+// the printer or paper can't do and nothing was printed, 500 when printing failed part way,
+// so a client that retries on 400 can't print the receipt twice
+func printErrorStatus(err error) int {
+	var verr *receipt.ValidationError
+	switch {
+	case errors.Is(err, errPrintFailed):
+		return 500
+	case errors.As(err, &verr),
+		errors.Is(err, render.ErrUnsupported),
+		errors.Is(err, render.ErrTooWide),
+		errors.Is(err, errNVGraphicNotLoaded):
+		return 400
+	default:
+		return 500
+	}
+}
+
+// errPrintFailed is returned once the printer failed part way through a receipt
+var errPrintFailed = errors.New("printing failed")
+
+// Print prints req on p like the print endpoints do, with the server's NV graphics.
+// It checks the printer can print all of req before sending anything, and returns errPrintFailed
+// if the printer fails after that. The caller must hold printerMutex, so copies can't be
+// interleaved with other jobs.
+func Print(p *render.ESCPOS, req receipt.Request) error {
+	if err := checkPrintable(p, req); err != nil {
+		return err
+	}
+	if err := render.Print(nvPrinter{p}, req); err != nil {
+		return fmt.Errorf("%w: %w", errPrintFailed, err)
+	}
+	return nil
+}
+
+// checkPrintable returns every item of req that p can't print, including NV images that aren't loaded
+func checkPrintable(p *render.ESCPOS, req receipt.Request) error {
+	errs := []error{render.Check(p.Features, req)}
+	for i, item := range req.Receipt {
+		if n, ok := item.(receipt.NVImage); ok {
+			if err := nvGraphics.Loaded(n.Key); err != nil {
+				errs = append(errs, fmt.Errorf("receipt item %d: %w", i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// nvPrinter prints NV images with the command of the stored graphics, which is FS p when they are legacy NV bit images
+type nvPrinter struct {
+	*render.ESCPOS
+}
+
+func (p nvPrinter) NVImage(n receipt.NVImage) error {
+	return nvGraphics.Print(p.ESCPOS, n)
+}
+
+// errorResponse is the body of an error response, with every field error if err is a ValidationError
+func errorResponse(err error) gin.H {
+	body := gin.H{"error": err.Error()}
+	var verr *receipt.ValidationError
+	if errors.As(err, &verr) {
+		body["errors"] = verr.Errors
+	}
+	return body
+}
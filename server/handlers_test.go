@@ -1,16 +1,19 @@
-package main
+package server
 
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/codaea/simpleprint/internal/testutil"
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
 	"github.com/gin-gonic/gin"
-	"github.com/mect/go-escpos"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,7 +22,7 @@ func setupTestRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	printer, err := escpos.NewUSBPrinterByPath("")
+	printer, err := render.Open("")
 	if err != nil {
 		panic("Failed to create mock printer: " + err.Error())
 	}
@@ -33,8 +36,8 @@ func setupTestRouter() *gin.Engine {
 		c.Set("printer", printer)
 		c.Next()
 	})
-
-	r.POST("/print", handlePrint)
+	r.Use(recordPrintedBodies)
+	Routes(r)
 	return r
 }
 
@@ -219,9 +222,9 @@ func TestHandlePrint_PrinterBusy(t *testing.T) {
 	// Lock the printer mutex to simulate busy printer
 	printerMutex.Lock()
 
-	receipt := PrintRequest{
-		Receipt: []ReceiptItem{
-			Line{Content: "Test", Font: FontA, Alignment: AlignLeft, FontSize: 1},
+	receipt := receipt.Request{
+		Receipt: []receipt.Item{
+			receipt.Line{Content: "Test", Font: receipt.FontA, Alignment: receipt.AlignLeft, FontSize: 1},
 		},
 	}
 
@@ -245,10 +248,10 @@ func TestHandlePrint_PrinterBusy(t *testing.T) {
 func TestHandlePrint_ConcurrentRequests(t *testing.T) {
 	router := setupTestRouter()
 
-	receipt := PrintRequest{
-		Receipt: []ReceiptItem{
-			Line{Content: "Concurrent Test", Font: FontA, Alignment: AlignCenter, FontSize: 1},
-			Feed{Lines: 3},
+	receipt := receipt.Request{
+		Receipt: []receipt.Item{
+			receipt.Line{Content: "Concurrent Test", Font: receipt.FontA, Alignment: receipt.AlignCenter, FontSize: 1},
+			receipt.Feed{Lines: 3},
 		},
 	}
 
@@ -305,7 +308,7 @@ func TestHandlePrint_CompleteReceipt(t *testing.T) {
 				"content": "STORE NAME",
 				"font": "A",
 				"alignment": "center",
-				"font-size": 2,
+				"font_size": 2,
 				"underline": true
 			},
 			{
@@ -313,7 +316,7 @@ func TestHandlePrint_CompleteReceipt(t *testing.T) {
 				"content": "123 Main St, City, State",
 				"font": "B",
 				"alignment": "center",
-				"font-size": 1,
+				"font_size": 1,
 				"underline": false
 			},
 			{
@@ -321,7 +324,7 @@ func TestHandlePrint_CompleteReceipt(t *testing.T) {
 				"content": "Tel: (555) 123-4567",
 				"font": "B",
 				"alignment": "center",
-				"font-size": 1,
+				"font_size": 1,
 				"underline": false
 			},
 			{
@@ -333,7 +336,7 @@ func TestHandlePrint_CompleteReceipt(t *testing.T) {
 				"content": "RECEIPT #12345",
 				"font": "A",
 				"alignment": "center",
-				"font-size": 1,
+				"font_size": 1,
 				"underline": false
 			},
 			{
@@ -345,7 +348,7 @@ func TestHandlePrint_CompleteReceipt(t *testing.T) {
 				"content": "Item 1",
 				"font": "B",
 				"alignment": "left",
-				"font-size": 1,
+				"font_size": 1,
 				"underline": false
 			},
 			{
@@ -353,7 +356,7 @@ func TestHandlePrint_CompleteReceipt(t *testing.T) {
 				"content": "$10.00",
 				"font": "B",
 				"alignment": "right",
-				"font-size": 1,
+				"font_size": 1,
 				"underline": false
 			},
 			{
@@ -361,7 +364,7 @@ func TestHandlePrint_CompleteReceipt(t *testing.T) {
 				"content": "Item 2",
 				"font": "B",
 				"alignment": "left",
-				"font-size": 1,
+				"font_size": 1,
 				"underline": false
 			},
 			{
@@ -369,7 +372,7 @@ func TestHandlePrint_CompleteReceipt(t *testing.T) {
 				"content": "$15.00",
 				"font": "B",
 				"alignment": "right",
-				"font-size": 1,
+				"font_size": 1,
 				"underline": false
 			},
 			{
@@ -381,7 +384,7 @@ func TestHandlePrint_CompleteReceipt(t *testing.T) {
 				"content": "--------------------------------",
 				"font": "B",
 				"alignment": "center",
-				"font-size": 1,
+				"font_size": 1,
 				"underline": false
 			},
 			{
@@ -389,7 +392,7 @@ func TestHandlePrint_CompleteReceipt(t *testing.T) {
 				"content": "TOTAL: $25.00",
 				"font": "A",
 				"alignment": "center",
-				"font-size": 1,
+				"font_size": 1,
 				"underline": true
 			},
 			{
@@ -435,7 +438,7 @@ func TestHandlePrint_WithImage(t *testing.T) {
 				"content": "Image Test",
 				"font": "A",
 				"alignment": "center",
-				"font-size": 1,
+				"font_size": 1,
 				"underline": false
 			},
 			{
@@ -483,7 +486,7 @@ func TestHandlePrint_WithImageNoDithering(t *testing.T) {
 				"content": "Image Test - No Dithering",
 				"font": "A",
 				"alignment": "center",
-				"font-size": 1,
+				"font_size": 1,
 				"underline": false
 			},
 			{
@@ -516,3 +519,48 @@ func TestHandlePrint_WithImageNoDithering(t *testing.T) {
 	json.Unmarshal(w.Body.Bytes(), &response)
 	assert.True(t, response["success"].(bool))
 }
+
+// failingDevice is a printer that has gone away, every write fails
+type failingDevice struct {
+	testutil.BufferDevice
+}
+
+func (d *failingDevice) Write(b []byte) (int, error) {
+	return 0, errors.New("printer unplugged")
+}
+
+func TestHandlePrint_PrintFails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := `{"receipt": [{"type": "line", "content": "Hello"}, {"type": "line", "content": "Привет", "code_page": "PC866"}]}`
+
+	printTo := func(p *render.ESCPOS) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.Use(UsePrinter(p))
+		r.POST("/print", handlePrint)
+		req, _ := http.NewRequest("POST", "/print", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// the printer failing part way is the server's problem, sending the request again could print it twice
+	broken, _ := render.NewESCPOS(&failingDevice{})
+	w := printTo(broken)
+	assert.Equal(t, 500, w.Code)
+	assert.Contains(t, w.Body.String(), "printer unplugged")
+
+	// a code page the printer doesn't have is the request's, and is found before anything is printed
+	p, dev := newBufferPrinter()
+	p.Features = render.Features{CodePages: []receipt.CodePage{receipt.CodePagePC437}}
+	w = printTo(p)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "receipt item 1: code page PC866: not supported by the printer")
+	assert.Zero(t, dev.Len())
+
+	nvGraphics = NewNVGraphics(t.TempDir(), false)
+	body = `{"receipt": [{"type": "line", "content": "Hello"}, {"type": "nv_image", "key": "LG"}]}`
+	w = printTo(p)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "no NV graphic loaded with key: LG")
+	assert.Zero(t, dev.Len())
+}
@@ -0,0 +1,470 @@
+package server
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/codaea/simpleprint/imaging"
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlBlock is a block of an HTML document laid out top to bottom:
+// a paragraph of text, a rule, an image or a table
+type htmlBlock struct {
+	kind      string
+	runs      []textRun
+	size      int
+	alignment receipt.AlignmentType
+	// margin blocks (paragraphs, headings and tables) have a blank line around them
+	margin bool
+	img    image.Image
+	width  int
+	rows   [][]string
+	header bool
+}
+
+// htmlParser collects the blocks of a document, text is added to cur until a block ends
+type htmlParser struct {
+	blocks []htmlBlock
+	cur    *htmlBlock
+}
+
+type htmlContext struct {
+	alignment receipt.AlignmentType
+	size      int
+	margin    bool
+	bold      bool
+	underline bool
+}
+
+// parseHTML parses the supported HTML subset: p, div, h1-h3, b/strong, u, br, hr, table and img with a data URI.
+// text-align in a style attribute or the align attribute sets the alignment of a block.
+// Other elements are printed as their text, script and style are dropped.
+func parseHTML(r io.Reader) ([]htmlBlock, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &htmlParser{}
+	body := receipt.TextStyle{}.WithDefaults()
+	if err := p.walk(doc, htmlContext{alignment: body.Alignment, size: body.FontSize}); err != nil {
+		return nil, err
+	}
+	p.endBlock()
+	return p.blocks, nil
+}
+
+func (p *htmlParser) endBlock() {
+	if p.cur != nil && strings.TrimSpace(plainText(p.cur.runs)) != "" {
+		runs := p.cur.runs
+		runs[0].text = strings.TrimLeft(runs[0].text, " \n")
+		runs[len(runs)-1].text = strings.TrimRight(runs[len(runs)-1].text, " \n")
+		p.blocks = append(p.blocks, *p.cur)
+	}
+	p.cur = nil
+}
+
+func (p *htmlParser) walk(n *html.Node, ctx htmlContext) error {
+	switch n.Type {
+	case html.TextNode:
+		// whitespace collapses to one space like in a browser
+		text := strings.Join(strings.Fields(n.Data), " ")
+		if text == "" {
+			text = " "
+		} else {
+			if unicode.IsSpace(rune(n.Data[0])) {
+				text = " " + text
+			}
+			if unicode.IsSpace(rune(n.Data[len(n.Data)-1])) {
+				text += " "
+			}
+		}
+		if p.cur == nil {
+			if strings.TrimSpace(text) == "" {
+				return nil
+			}
+			p.cur = &htmlBlock{kind: "text", size: ctx.size, alignment: ctx.alignment, margin: ctx.margin}
+		}
+		p.cur.runs = appendRun(p.cur.runs, textRun{text, ctx.bold, ctx.underline})
+		return nil
+	case html.ElementNode:
+	default:
+		return p.walkChildren(n, ctx)
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Title:
+		return nil
+	case atom.B, atom.Strong:
+		ctx.bold = true
+	case atom.U, atom.Ins:
+		ctx.underline = true
+	case atom.Br:
+		if p.cur == nil {
+			p.cur = &htmlBlock{kind: "text", size: ctx.size, alignment: ctx.alignment, margin: ctx.margin}
+		}
+		p.cur.runs = append(p.cur.runs, textRun{text: "\n"})
+		return nil
+	case atom.Hr:
+		p.endBlock()
+		p.blocks = append(p.blocks, htmlBlock{kind: "rule"})
+		return nil
+	case atom.Img:
+		p.endBlock()
+		return p.image(n, ctx)
+	case atom.Table:
+		p.endBlock()
+		p.table(n, ctx)
+		return nil
+	case atom.P, atom.Div, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		p.endBlock()
+		ctx.alignment = blockAlignment(n, ctx.alignment)
+		ctx.margin = n.DataAtom != atom.Div
+		switch n.DataAtom {
+		case atom.H1, atom.H2:
+			ctx.size, ctx.bold = max(2, ctx.size), true
+		case atom.H3, atom.H4, atom.H5, atom.H6:
+			ctx.bold = true
+		}
+		if err := p.walkChildren(n, ctx); err != nil {
+			return err
+		}
+		p.endBlock()
+		return nil
+	}
+	return p.walkChildren(n, ctx)
+}
+
+func (p *htmlParser) walkChildren(n *html.Node, ctx htmlContext) error {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if err := p.walk(child, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *htmlParser) image(n *html.Node, ctx htmlContext) error {
+	src := htmlAttr(n, "src")
+	if !strings.HasPrefix(src, "data:") {
+		return fmt.Errorf("img src must be a data URI")
+	}
+	img, err := imaging.Decode(src)
+	if err != nil {
+		return fmt.Errorf("error decoding img: %v", err)
+	}
+	width, _ := strconv.Atoi(strings.TrimSuffix(htmlAttr(n, "width"), "px"))
+	p.blocks = append(p.blocks, htmlBlock{kind: "image", img: img, width: width, alignment: ctx.alignment})
+	return nil
+}
+
+func (p *htmlParser) table(n *html.Node, ctx htmlContext) {
+	block := htmlBlock{kind: "table", alignment: ctx.alignment, margin: true}
+	var rows func(n *html.Node)
+	rows = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if child.DataAtom != atom.Tr {
+				rows(child)
+				continue
+			}
+			var row []string
+			header := true
+			for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+					row = append(row, strings.Join(strings.Fields(htmlText(cell)), " "))
+					header = header && cell.DataAtom == atom.Th
+				}
+			}
+			if len(row) > 0 {
+				if len(block.rows) == 0 {
+					block.header = header
+				}
+				block.rows = append(block.rows, row)
+			}
+		}
+	}
+	rows(n)
+	if len(block.rows) > 0 {
+		p.blocks = append(p.blocks, block)
+	}
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// htmlText returns all the text inside n
+func htmlText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var s strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		s.WriteString(htmlText(child) + " ")
+	}
+	return s.String()
+}
+
+// blockAlignment reads text-align from the style attribute, or the old align attribute
+func blockAlignment(n *html.Node, inherited receipt.AlignmentType) receipt.AlignmentType {
+	value := htmlAttr(n, "align")
+	for _, decl := range strings.Split(htmlAttr(n, "style"), ";") {
+		if prop, v, ok := strings.Cut(decl, ":"); ok && strings.TrimSpace(strings.ToLower(prop)) == "text-align" {
+			value = v
+		}
+	}
+	if alignment, err := receipt.ParseAlignment(strings.TrimSpace(strings.ToLower(value))); err == nil {
+		return alignment
+	}
+	return inherited
+}
+
+// htmlToItems lays out the blocks as receipt items printed with ESC/POS text commands, in the profile's default font
+func htmlToItems(blocks []htmlBlock) ([]receipt.Item, error) {
+	font := receipt.TextStyle{}.WithDefaults().Font
+	var items []receipt.Item
+	for i, block := range blocks {
+		if i > 0 && (block.margin || blocks[i-1].margin) {
+			items = append(items, receipt.Feed{Type: "feed", Lines: 1})
+		}
+		switch block.kind {
+		case "text":
+			lines := wrapRuns(block.runs, receipt.CharsPerLine(font, block.size), "", "")
+			items = append(items, runsToItems(lines, receipt.TextStyle{Font: font, FontSize: block.size, Alignment: block.alignment})...)
+		case "rule":
+			items = append(items, separator())
+		case "image":
+			items = append(items, receipt.Image{Type: "image", Alignment: block.alignment, DitherMode: receipt.DitherFloydSteinberg, Width: block.width, Img: block.img})
+		case "table":
+			table, err := tableToColumns(block.rows, nil, block.header)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, table...)
+		}
+	}
+	return items, nil
+}
+
+// rasterFonts are the faces text is drawn with, font A is 12x24 dots so text is 20px at size 1.
+// Tables use a monospaced font, 12 dots wide like font A, so their columns line up.
+type rasterFonts struct {
+	*render.Faces
+}
+
+func newRasterFonts() (*rasterFonts, error) {
+	faces, err := render.NewFaces(map[string][]byte{"regular": goregular.TTF, "bold": gobold.TTF, "mono": gomono.TTF, "monobold": gomonobold.TTF})
+	if err != nil {
+		return nil, err
+	}
+	return &rasterFonts{faces}, nil
+}
+
+// face returns the face of font name at size
+func (f *rasterFonts) face(name string, size int) font.Face {
+	return f.Face(name, float64(20*size))
+}
+
+// text returns the face for a run of text, regular or bold
+func (f *rasterFonts) text(size int) func(bold bool) font.Face {
+	return func(bold bool) font.Face {
+		if bold {
+			return f.face("bold", size)
+		}
+		return f.face("regular", size)
+	}
+}
+
+// mono returns the face for a table row
+func (f *rasterFonts) mono() func(bold bool) font.Face {
+	return func(bold bool) font.Face {
+		if bold {
+			return f.face("monobold", 1)
+		}
+		return f.face("mono", 1)
+	}
+}
+
+// rasterizeHTML draws the blocks as one image as wide as the paper, for printers
+// without the fonts or characters a document needs. Images in the document are dithered.
+func rasterizeHTML(blocks []htmlBlock) (image.Image, error) {
+	fonts, err := newRasterFonts()
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []image.Image
+	for i, block := range blocks {
+		if i > 0 && (block.margin || blocks[i-1].margin) {
+			parts = append(parts, image.NewRGBA(image.Rect(0, 0, receipt.PaperWidth, 24)))
+		}
+		switch block.kind {
+		case "text":
+			parts = append(parts, fonts.drawText(block))
+		case "rule":
+			rule := image.NewRGBA(image.Rect(0, 0, receipt.PaperWidth, 24))
+			draw.Draw(rule, image.Rect(0, 11, receipt.PaperWidth, 13), image.Black, image.Point{}, draw.Src)
+			parts = append(parts, rule)
+		case "image":
+			img := receipt.Image{DitherMode: receipt.DitherFloydSteinberg, Width: block.width, Img: block.img}.Bitmap()
+			b := img.Bounds()
+			part := image.NewRGBA(image.Rect(0, 0, receipt.PaperWidth, b.Dy()))
+			draw.Draw(part, image.Rect(render.AlignX(b.Dx(), block.alignment), 0, receipt.PaperWidth, b.Dy()), img, b.Min, draw.Src)
+			parts = append(parts, part)
+		case "table":
+			table, err := tableToColumns(block.rows, nil, block.header)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range table {
+				cols := item.(receipt.Columns)
+				parts = append(parts, drawRuns([]textRun{{text: cols.Layout(), bold: cols.Bold}}, 1, receipt.AlignLeft, fonts.mono()))
+			}
+		}
+	}
+
+	height := 0
+	for _, part := range parts {
+		height += part.Bounds().Dy()
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, receipt.PaperWidth, max(1, height)))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	y := 0
+	for _, part := range parts {
+		b := part.Bounds()
+		draw.Draw(canvas, image.Rect(0, y, receipt.PaperWidth, y+b.Dy()), part, b.Min, draw.Over)
+		y += b.Dy()
+	}
+	return canvas, nil
+}
+
+// drawText word wraps a text block to the paper by the measured width of each word
+func (f *rasterFonts) drawText(block htmlBlock) image.Image {
+	face := f.text(block.size)
+
+	var lines [][]textRun
+	var line []textRun
+	for _, word := range splitWords(block.runs) {
+		if isLineBreak(word) {
+			lines = append(lines, line)
+			line = nil
+			continue
+		}
+		if len(line) > 0 {
+			candidate := append(append([]textRun{}, line...), textRun{text: " "})
+			if measureRuns(append(candidate, word...), face) > receipt.PaperWidth {
+				lines = append(lines, line)
+				line = nil
+			}
+		}
+		if len(line) > 0 {
+			line = appendRun(line, wordSpace(line[len(line)-1], word[0]))
+		}
+		for _, run := range word {
+			line = appendRun(line, run)
+		}
+	}
+	lines = append(lines, line)
+
+	img := image.NewRGBA(image.Rect(0, 0, receipt.PaperWidth, 24*block.size*len(lines)))
+	for i, line := range lines {
+		l := drawRuns(line, block.size, block.alignment, face)
+		draw.Draw(img, image.Rect(0, i*24*block.size, receipt.PaperWidth, (i+1)*24*block.size), l, image.Point{}, draw.Over)
+	}
+	return img
+}
+
+func measureRuns(runs []textRun, face func(bold bool) font.Face) int {
+	width := 0
+	for _, run := range runs {
+		width += font.MeasureString(face(run.bold), run.text).Ceil()
+	}
+	return width
+}
+
+// drawRuns draws one line of text 24 dots high per size
+func drawRuns(runs []textRun, size int, alignment receipt.AlignmentType, face func(bold bool) font.Face) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, receipt.PaperWidth, 24*size))
+	x := fixed.I(render.AlignX(measureRuns(runs, face), alignment))
+	baseline := 19 * size
+	for _, run := range runs {
+		d := font.Drawer{Dst: img, Src: image.Black, Face: face(run.bold), Dot: fixed.Point26_6{X: x, Y: fixed.I(baseline)}}
+		d.DrawString(run.text)
+		if run.underline {
+			draw.Draw(img, image.Rect(x.Floor(), baseline+2, d.Dot.X.Ceil(), baseline+2+size), image.Black, image.Point{}, draw.Src)
+		}
+		x = d.Dot.X
+	}
+	return img
+}
+
+// htmlToReceipt converts an HTML document to a print request, mode is text or raster
+func htmlToReceipt(r io.Reader, mode string) (receipt.Request, error) {
+	blocks, err := parseHTML(r)
+	if err != nil {
+		return receipt.Request{}, err
+	}
+
+	req := receipt.Request{Cut: receipt.CutFull, Copies: 1}
+	switch mode {
+	case "", "text":
+		req.Receipt, err = htmlToItems(blocks)
+		if err != nil {
+			return receipt.Request{}, err
+		}
+	case "raster":
+		img, err := rasterizeHTML(blocks)
+		if err != nil {
+			return receipt.Request{}, err
+		}
+		// printed like any image item, Image.Bitmap scales it to the paper with imaging.Scale and leaves it undithered
+		req.Receipt = []receipt.Item{receipt.Image{Type: "image", Alignment: receipt.AlignLeft, DitherMode: receipt.DitherNone, Img: img}}
+	default:
+		return receipt.Request{}, fmt.Errorf("invalid mode: %s. Must be text or raster", mode)
+	}
+	return req, nil
+}
+
+// handlePrintHTML prints the HTML document in the body, ?mode=raster draws it as an image
+func handlePrintHTML(c *gin.Context) {
+	req, err := htmlToReceipt(c.Request.Body, c.Query("mode"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !tryLockPrinter(c) {
+		return
+	}
+	defer printerMutex.Unlock()
+
+	p := c.MustGet("printer").(*render.ESCPOS)
+	if err := Print(p, req); err != nil {
+		c.JSON(printErrorStatus(err), errorResponse(err))
+		return
+	}
+	c.JSON(200, gin.H{"success": true})
+}
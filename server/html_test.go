@@ -0,0 +1,89 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codaea/simpleprint/imaging"
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/stretchr/testify/assert"
+)
+
+const orderHTML = `<html><head><style>p { color: red }</style></head><body>
+<h1 style="text-align: center">Order   confirmed</h1>
+<p>Thanks <b>Sam</b>,<br>your order is <u>on its way</u>.</p>
+<hr>
+<table>
+  <tr><th>Item</th><th>Price</th></tr>
+  <tr><td>Coffee</td><td>$3.50</td></tr>
+</table>
+<div align="right">Total: $3.50</div>
+</body></html>`
+
+func TestHTMLToReceipt_Text(t *testing.T) {
+	req, err := htmlToReceipt(strings.NewReader(orderHTML), "text")
+	assert.NoError(t, err)
+
+	assert.Equal(t, receipt.Line{Type: "line", Content: "Order confirmed", FontSize: 2, Font: receipt.FontA, Alignment: receipt.AlignCenter, Bold: true}, req.Receipt[0])
+	assert.Equal(t, receipt.Feed{Type: "feed", Lines: 1}, req.Receipt[1])
+	assert.Equal(t, "Thanks ", req.Receipt[2].(receipt.Text).Content)
+	assert.True(t, req.Receipt[3].(receipt.Text).Bold)
+	assert.Equal(t, ",\n", req.Receipt[4].(receipt.Text).Content, "br starts a new line")
+	assert.Equal(t, "your order is ", req.Receipt[5].(receipt.Text).Content)
+	assert.True(t, req.Receipt[6].(receipt.Text).Underline)
+	assert.Equal(t, separator(), req.Receipt[9])
+
+	header := req.Receipt[11].(receipt.Columns)
+	assert.True(t, header.Bold, "a row of th is a header")
+	assert.Equal(t, "Coffee", req.Receipt[12].(receipt.Columns).Columns[0].Content)
+
+	total := req.Receipt[14].(receipt.Line)
+	assert.Equal(t, "Total: $3.50", total.Content)
+	assert.Equal(t, receipt.AlignRight, total.Alignment)
+	assert.Len(t, req.Receipt, 15)
+}
+
+func TestHTMLToReceipt_ProfileDefaults(t *testing.T) {
+	restoreSettings(t)
+	receipt.DefaultStyle = receipt.TextStyle{Font: receipt.FontB}
+	receipt.DefaultCodePage = receipt.CodePagePC850
+
+	req, err := htmlToReceipt(strings.NewReader("<p>Café</p>"), "text")
+	assert.NoError(t, err)
+	assert.Equal(t, receipt.Line{Type: "line", Content: "Café", FontSize: 1, Font: receipt.FontB, Alignment: receipt.AlignLeft, CodePage: receipt.CodePagePC850}, req.Receipt[0])
+}
+
+func TestHTMLToReceipt_Raster(t *testing.T) {
+	req, err := htmlToReceipt(strings.NewReader(orderHTML+`<img src="`+testPNG(100, 50)+`">`), "raster")
+	assert.NoError(t, err)
+	assert.Len(t, req.Receipt, 1)
+
+	img := req.Receipt[0].(receipt.Image)
+	assert.Equal(t, receipt.PaperWidth, img.Img.Bounds().Dx())
+	assert.Greater(t, img.Img.Bounds().Dy(), 50)
+
+	_, height, pixels := imaging.BlackPixels(img.Img)
+	inked := 0
+	for y := 0; y < height; y++ {
+		for _, black := range pixels[y] {
+			if black {
+				inked++
+			}
+		}
+	}
+	assert.Greater(t, inked, 1000, "text is drawn")
+}
+
+func TestHTMLToReceipt_Errors(t *testing.T) {
+	_, err := htmlToReceipt(strings.NewReader(`<img src="https://example.com/logo.png">`), "text")
+	assert.ErrorContains(t, err, "data URI")
+
+	_, err = htmlToReceipt(strings.NewReader(`<p>hi</p>`), "pdf")
+	assert.ErrorContains(t, err, "invalid mode")
+}
+
+func TestPrintHTML(t *testing.T) {
+	router := setupTestRouter()
+	w := doRequest(router, "POST", "/print/html?mode=raster", `<p>Hello <b>world</b></p>`)
+	assert.Equal(t, 200, w.Code)
+}
@@ -0,0 +1,555 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/codaea/simpleprint/imaging"
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/gin-gonic/gin"
+)
+
+// textRun is a piece of text printed in one style
+type textRun struct {
+	text      string
+	bold      bool
+	underline bool
+}
+
+func (r textRun) sameStyle(o textRun) bool {
+	return r.bold == o.bold && r.underline == o.underline
+}
+
+// appendRun adds run to runs, joining it to the last run if they have the same style
+func appendRun(runs []textRun, run textRun) []textRun {
+	if run.text == "" {
+		return runs
+	}
+	if n := len(runs); n > 0 && runs[n-1].sameStyle(run) {
+		runs[n-1].text += run.text
+		return runs
+	}
+	return append(runs, run)
+}
+
+func isLineBreak(word []textRun) bool {
+	return len(word) == 1 && word[0].text == "\n"
+}
+
+func runsLen(runs []textRun) int {
+	n := 0
+	for _, run := range runs {
+		n += utf8.RuneCountInString(run.text)
+	}
+	return n
+}
+
+// splitRuns splits runs after n characters
+func splitRuns(runs []textRun, n int) (head, tail []textRun) {
+	for _, run := range runs {
+		runes := []rune(run.text)
+		switch {
+		case n <= 0:
+			tail = append(tail, run)
+		case len(runes) <= n:
+			head = append(head, run)
+		default:
+			head = append(head, textRun{string(runes[:n]), run.bold, run.underline})
+			tail = append(tail, textRun{string(runes[n:]), run.bold, run.underline})
+		}
+		n -= len(runes)
+	}
+	return head, tail
+}
+
+// lineBreak is the word splitWords returns for a newline
+var lineBreak = []textRun{{text: "\n"}}
+
+// splitWords splits runs at spaces, a word can be in more than one style like **bo**ld.
+// Newlines become a lineBreak word.
+func splitWords(runs []textRun) [][]textRun {
+	var words [][]textRun
+	var word []textRun
+	for _, run := range runs {
+		for j, segment := range strings.Split(strings.ReplaceAll(run.text, "\t", " "), "\n") {
+			if j > 0 {
+				if len(word) > 0 {
+					words = append(words, word)
+					word = nil
+				}
+				words = append(words, lineBreak)
+			}
+			for i, part := range strings.Split(segment, " ") {
+				if i > 0 && len(word) > 0 {
+					words = append(words, word)
+					word = nil
+				}
+				word = appendRun(word, textRun{part, run.bold, run.underline})
+			}
+		}
+	}
+	if len(word) > 0 {
+		words = append(words, word)
+	}
+	return words
+}
+
+// wordSpace is the space between two words, it is only styled when the words on both sides are
+func wordSpace(before, after textRun) textRun {
+	space := textRun{text: " "}
+	if before.sameStyle(after) {
+		space.bold, space.underline = before.bold, before.underline
+	}
+	return space
+}
+
+// wrapRuns word wraps runs to width characters. The first line starts with prefix,
+// the others with indent, words longer than a line are broken and newlines start a new line.
+func wrapRuns(runs []textRun, width int, prefix, indent string) [][]textRun {
+	var lines [][]textRun
+	line := appendRun(nil, textRun{text: prefix})
+	lineLen := utf8.RuneCountInString(prefix)
+	hasWord := false
+	newLine := func() {
+		lines = append(lines, line)
+		line = appendRun(nil, textRun{text: indent})
+		lineLen = utf8.RuneCountInString(indent)
+		hasWord = false
+	}
+
+	for _, word := range splitWords(runs) {
+		if isLineBreak(word) {
+			newLine()
+			continue
+		}
+		n := runsLen(word)
+		if hasWord && lineLen+1+n > width {
+			newLine()
+		}
+		if hasWord {
+			line = appendRun(line, wordSpace(line[len(line)-1], word[0]))
+			lineLen++
+		}
+		for lineLen+n > width && width-lineLen > 0 {
+			head, tail := splitRuns(word, width-lineLen)
+			for _, run := range head {
+				line = appendRun(line, run)
+			}
+			n -= width - lineLen
+			word = tail
+			newLine()
+		}
+		for _, run := range word {
+			line = appendRun(line, run)
+		}
+		lineLen += n
+		hasWord = true
+	}
+	return append(lines, line)
+}
+
+// runsToItems turns wrapped lines into receipt items, a line item for lines in one style
+// and consecutive text items for lines that change style, in the profile's default code page
+func runsToItems(lines [][]textRun, style receipt.TextStyle) []receipt.Item {
+	var items []receipt.Item
+	for _, line := range lines {
+		if len(line) <= 1 {
+			var run textRun
+			if len(line) == 1 {
+				run = line[0]
+			}
+			items = append(items, receipt.Line{Type: "line", Content: run.text, FontSize: style.FontSize, Font: style.Font,
+				Alignment: style.Alignment, Underline: run.underline, Bold: run.bold, CodePage: receipt.DefaultCodePage})
+			continue
+		}
+		for i, run := range line {
+			if i == len(line)-1 {
+				run.text += "\n"
+			}
+			items = append(items, receipt.Text{Type: "text", Content: run.text, FontSize: style.FontSize, Font: style.Font,
+				Alignment: style.Alignment, Underline: run.underline, Bold: run.bold, CodePage: receipt.DefaultCodePage})
+		}
+	}
+	return items
+}
+
+// separator is a rule across the paper
+func separator() receipt.Line {
+	return receipt.Line{Type: "line", Content: strings.Repeat("-", receipt.CharsPerLine(receipt.FontA, 1)), FontSize: 1, Font: receipt.FontA, Alignment: receipt.AlignLeft, CodePage: receipt.DefaultCodePage}
+}
+
+var (
+	mdHeading   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdRule      = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	mdFence     = regexp.MustCompile("^\\s*(```|~~~)")
+	mdListItem  = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	mdImage     = regexp.MustCompile(`^\s*!\[([^\]]*)\]\(([^)\s]+)\)\s*$`)
+	mdQuote     = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	mdTableSep  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdLink      = regexp.MustCompile(`^\[([^\]]*)\]\(([^)\s]*)\)`)
+	mdTableCell = regexp.MustCompile(`^\s*\|`)
+)
+
+// parseInline splits the inline formatting of markdown text into runs.
+// ** and __ are bold, * is emphasis which is underlined since printers have no italics.
+// Code spans lose their backticks and links are printed as text (url).
+func parseInline(s string) []textRun {
+	var runs []textRun
+	var cur strings.Builder
+	bold, underline := false, false
+	flush := func() {
+		runs = appendRun(runs, textRun{cur.String(), bold, underline})
+		cur.Reset()
+	}
+
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			// a marker is only formatting if it is closed again
+			if bold || strings.Contains(rest[2:], rest[:2]) {
+				flush()
+				bold = !bold
+				i += 2
+				continue
+			}
+		case rest[0] == '*':
+			if underline || strings.Contains(rest[1:], "*") {
+				flush()
+				underline = !underline
+				i++
+				continue
+			}
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				cur.WriteString(rest[1 : end+1])
+				i += end + 2
+				continue
+			}
+		case rest[0] == '[':
+			if m := mdLink.FindStringSubmatch(rest); m != nil {
+				cur.WriteString(m[1])
+				if m[2] != "" && m[2] != m[1] {
+					cur.WriteString(" (" + m[2] + ")")
+				}
+				i += len(m[0])
+				continue
+			}
+		}
+		cur.WriteByte(s[i])
+		i++
+	}
+	flush()
+	return runs
+}
+
+// plainText drops the formatting of runs
+func plainText(runs []textRun) string {
+	var s strings.Builder
+	for _, run := range runs {
+		s.WriteString(run.text)
+	}
+	return s.String()
+}
+
+func isTableRow(line string) bool {
+	return mdTableCell.MatchString(line)
+}
+
+// isBlockStart reports if line starts something other than a paragraph
+func isBlockStart(line string) bool {
+	return mdHeading.MatchString(line) || mdRule.MatchString(line) || mdFence.MatchString(line) ||
+		mdListItem.MatchString(line) || mdImage.MatchString(line) || mdQuote.MatchString(line) || isTableRow(line)
+}
+
+// markdownToReceipt converts markdown to a print request: headings become big bold lines,
+// lists and quotes are indented and wrapped, rules are separators, code blocks use font B,
+// tables become columns and images on their own line become image items
+// (a data URI or the name of an asset). Other text is in the profile's default style.
+func markdownToReceipt(md string) (receipt.Request, error) {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	body := receipt.TextStyle{}.WithDefaults()
+	width := receipt.CharsPerLine(body.Font, body.FontSize)
+
+	var items []receipt.Item
+	blank := false
+	emit := func(block ...receipt.Item) {
+		// blank lines between blocks are kept, but only one
+		if blank && len(items) > 0 {
+			items = append(items, receipt.Feed{Type: "feed", Lines: 1})
+		}
+		blank = false
+		items = append(items, block...)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if strings.TrimSpace(line) == "" {
+			blank = true
+			continue
+		}
+
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			var block []receipt.Item
+			codeWidth := receipt.CharsPerLine(receipt.FontB, 1)
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]); i++ {
+				code := []rune(strings.ReplaceAll(lines[i], "\t", "    "))
+				for {
+					n := min(len(code), codeWidth)
+					block = append(block, receipt.Line{Type: "line", Content: string(code[:n]), FontSize: 1, Font: receipt.FontB, Alignment: receipt.AlignLeft, CodePage: receipt.DefaultCodePage})
+					code = code[n:]
+					if len(code) == 0 {
+						break
+					}
+				}
+			}
+			emit(block...)
+			continue
+		}
+
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			style := body
+			switch len(m[1]) {
+			case 1:
+				style.FontSize, style.Alignment = max(2, body.FontSize), receipt.AlignCenter
+			case 2:
+				style.FontSize = max(2, body.FontSize)
+			}
+			runs := parseInline(m[2])
+			for i := range runs {
+				runs[i].bold = true
+			}
+			emit(runsToItems(wrapRuns(runs, receipt.CharsPerLine(style.Font, style.FontSize), "", ""), style)...)
+			continue
+		}
+
+		if mdRule.MatchString(line) {
+			emit(separator())
+			continue
+		}
+
+		if m := mdImage.FindStringSubmatch(line); m != nil {
+			img, err := markdownImage(m[2])
+			if err != nil {
+				return receipt.Request{}, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			emit(img)
+			continue
+		}
+
+		if isTableRow(line) && i+1 < len(lines) && mdTableSep.MatchString(lines[i+1]) {
+			start := i
+			rows := [][]string{tableCells(line)}
+			alignments := tableAlignments(lines[i+1])
+			for i += 2; i < len(lines) && isTableRow(lines[i]); i++ {
+				rows = append(rows, tableCells(lines[i]))
+			}
+			i--
+			table, err := tableToColumns(rows, alignments, true)
+			if err != nil {
+				return receipt.Request{}, fmt.Errorf("line %d: %w", start+1, err)
+			}
+			emit(table...)
+			continue
+		}
+
+		if m := mdListItem.FindStringSubmatch(line); m != nil {
+			marker := m[2]
+			if strings.ContainsAny(marker, "*+") {
+				marker = "-"
+			}
+			indent := strings.Repeat(" ", len(strings.ReplaceAll(m[1], "\t", "  ")))
+			prefix := indent + marker + " "
+			text := m[3]
+			// lines that aren't a new block continue the item
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" && !isBlockStart(lines[i+1]) {
+				i++
+				text += " " + strings.TrimSpace(lines[i])
+			}
+			emit(runsToItems(wrapRuns(parseInline(text), width, prefix, strings.Repeat(" ", len(prefix))), body)...)
+			continue
+		}
+
+		if m := mdQuote.FindStringSubmatch(line); m != nil {
+			text := m[1]
+			for i+1 < len(lines) && mdQuote.MatchString(lines[i+1]) {
+				i++
+				text += " " + mdQuote.FindStringSubmatch(lines[i])[1]
+			}
+			emit(runsToItems(wrapRuns(parseInline(text), width, "| ", "| "), body)...)
+			continue
+		}
+
+		text := strings.TrimSpace(line)
+		for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" && !isBlockStart(lines[i+1]) {
+			i++
+			text += " " + strings.TrimSpace(lines[i])
+		}
+		emit(runsToItems(wrapRuns(parseInline(text), width, "", ""), body)...)
+	}
+
+	return receipt.Request{Receipt: items, Cut: receipt.CutFull, Copies: 1}, nil
+}
+
+// markdownImage makes an image item of a data URI, or of the asset named src
+func markdownImage(src string) (receipt.Image, error) {
+	item := receipt.Image{Type: "image", Alignment: receipt.AlignCenter}
+	var img image.Image
+	var err error
+	if strings.HasPrefix(src, "data:") {
+		item.Data = src
+		item.DitherMode = "floydsteinberg"
+		img, err = imaging.Decode(src)
+	} else {
+		item.Asset = src
+		img, err = assetStore.Load(src)
+	}
+	if err != nil {
+		return receipt.Image{}, err
+	}
+	item.Img = img
+	return item, nil
+}
+
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		cells[i] = plainText(parseInline(strings.TrimSpace(cell)))
+	}
+	return cells
+}
+
+func tableAlignments(sep string) []receipt.AlignmentType {
+	var alignments []receipt.AlignmentType
+	for _, cell := range tableCells(sep) {
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			alignments = append(alignments, receipt.AlignCenter)
+		case strings.HasSuffix(cell, ":"):
+			alignments = append(alignments, receipt.AlignRight)
+		default:
+			alignments = append(alignments, receipt.AlignLeft)
+		}
+	}
+	return alignments
+}
+
+// tableToColumns lays out a table as one columns item per row, with a bold first row if it is a header.
+// Columns are as wide as their longest cell, and shrunk to fit the paper if needed.
+// The first column gets any space left over. When there are more columns than fit on the paper,
+// the ones that don't fit are merged into the last one that does.
+func tableToColumns(rows [][]string, alignments []receipt.AlignmentType, header bool) ([]receipt.Item, error) {
+	cpl := receipt.CharsPerLine(receipt.FontA, 1)
+	n := len(rows[0])
+	// every column is at least one character wide, with a gap before all but the first
+	if fit := (cpl + receipt.ColumnGap) / (1 + receipt.ColumnGap); n > fit {
+		n = max(1, fit)
+		merged := make([][]string, len(rows))
+		for r, row := range rows {
+			if len(row) > n {
+				row = append(row[:n-1:n-1], strings.Join(row[n-1:], " "))
+			}
+			merged[r] = row
+		}
+		rows = merged
+	}
+	available := cpl - receipt.ColumnGap*(n-1)
+	widths := make([]int, n)
+	total := 0
+	for _, row := range rows {
+		for i := 0; i < n && i < len(row); i++ {
+			widths[i] = max(widths[i], utf8.RuneCountInString(row[i]))
+		}
+	}
+	for i := range widths {
+		widths[i] = max(1, widths[i])
+		total += widths[i]
+	}
+	if total > available {
+		shrunk := 0
+		for i := range widths {
+			widths[i] = max(1, widths[i]*available/total)
+			shrunk += widths[i]
+		}
+		total = shrunk
+	}
+	// the columns kept at 1 can leave the total over, take it from the widest
+	for total > available {
+		widest := 0
+		for i := range widths {
+			if widths[i] > widths[widest] {
+				widest = i
+			}
+		}
+		widths[widest]--
+		total--
+	}
+	widths[0] += available - total
+
+	items := make([]receipt.Item, len(rows))
+	for r, row := range rows {
+		cols := make([]receipt.Column, n)
+		for i := range cols {
+			cols[i] = receipt.Column{Width: widths[i], Alignment: receipt.AlignLeft}
+			if i < len(row) {
+				cols[i].Content = row[i]
+			}
+			if i < len(alignments) {
+				cols[i].Alignment = alignments[i]
+			}
+		}
+		item, err := checkColumns(receipt.Columns{Type: "columns", Columns: cols, FontSize: 1, Font: receipt.FontA, Bold: header && r == 0})
+		if err != nil {
+			return nil, fmt.Errorf("table row %d: %w", r+1, err)
+		}
+		items[r] = item
+	}
+	return items, nil
+}
+
+// checkColumns validates a columns item the way it is validated in a print request
+func checkColumns(c receipt.Columns) (receipt.Columns, error) {
+	body, err := json.Marshal(c)
+	if err != nil {
+		return receipt.Columns{}, err
+	}
+	var checked receipt.Columns
+	err = json.Unmarshal(body, &checked)
+	return checked, err
+}
+
+// handlePrintMarkdown prints the markdown document in the body
+func handlePrintMarkdown(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	req, err := markdownToReceipt(string(body))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !tryLockPrinter(c) {
+		return
+	}
+	defer printerMutex.Unlock()
+
+	p := c.MustGet("printer").(*render.ESCPOS)
+	if err := Print(p, req); err != nil {
+		c.JSON(printErrorStatus(err), errorResponse(err))
+		return
+	}
+	c.JSON(200, gin.H{"success": true})
+}
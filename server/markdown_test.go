@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownToReceipt(t *testing.T) {
+	req, err := markdownToReceipt(strings.Join([]string{
+		"# Daily Report",
+		"",
+		"Sales were **up** today.",
+		"",
+		"- first item that is long enough to wrap onto a second line of the receipt",
+		"  1. nested",
+		"",
+		"---",
+		"```",
+		"total = 42",
+		"```",
+	}, "\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, receipt.Line{Type: "line", Content: "Daily Report", FontSize: 2, Font: receipt.FontA, Alignment: receipt.AlignCenter, Bold: true}, req.Receipt[0])
+	assert.Equal(t, receipt.Feed{Type: "feed", Lines: 1}, req.Receipt[1])
+
+	// a line that changes style is printed as text items
+	assert.Equal(t, "Sales were ", req.Receipt[2].(receipt.Text).Content)
+	assert.True(t, req.Receipt[3].(receipt.Text).Bold)
+	assert.Equal(t, " today.\n", req.Receipt[4].(receipt.Text).Content)
+
+	assert.Equal(t, "- first item that is long enough to wrap onto a", req.Receipt[6].(receipt.Line).Content)
+	assert.Equal(t, "  second line of the receipt", req.Receipt[7].(receipt.Line).Content)
+	assert.Equal(t, "  1. nested", req.Receipt[8].(receipt.Line).Content)
+
+	assert.Equal(t, strings.Repeat("-", 48), req.Receipt[10].(receipt.Line).Content)
+	assert.Equal(t, receipt.Line{Type: "line", Content: "total = 42", FontSize: 1, Font: receipt.FontB, Alignment: receipt.AlignLeft}, req.Receipt[11])
+	assert.Len(t, req.Receipt, 12)
+	assert.Equal(t, receipt.CutFull, req.Cut)
+}
+
+func TestMarkdownToReceipt_ProfileDefaults(t *testing.T) {
+	restoreSettings(t)
+	receipt.DefaultStyle = receipt.TextStyle{Font: receipt.FontB}
+	receipt.DefaultCodePage = receipt.CodePagePC850
+
+	req, err := markdownToReceipt("# Menü\n\nCafé crème\n\n```\nçà\n```")
+	assert.NoError(t, err)
+	assert.Equal(t, receipt.Line{Type: "line", Content: "Menü", FontSize: 2, Font: receipt.FontB, Alignment: receipt.AlignCenter, Bold: true, CodePage: receipt.CodePagePC850}, req.Receipt[0])
+	assert.Equal(t, receipt.Line{Type: "line", Content: "Café crème", FontSize: 1, Font: receipt.FontB, Alignment: receipt.AlignLeft, CodePage: receipt.CodePagePC850}, req.Receipt[2])
+	assert.Equal(t, receipt.CodePagePC850, req.Receipt[4].(receipt.Line).CodePage)
+}
+
+func TestMarkdownToReceipt_Table(t *testing.T) {
+	req, err := markdownToReceipt("| Item | Qty | Price |\n|------|:---:|------:|\n| Coffee | 2 | $7.00 |\n| **Bagel** | 1 | $3.50 |")
+	assert.NoError(t, err)
+	assert.Len(t, req.Receipt, 3)
+
+	header := req.Receipt[0].(receipt.Columns)
+	assert.True(t, header.Bold)
+	assert.Equal(t, []int{38, 3, 5}, header.Widths(), "the first column gets the space left over")
+	assert.Equal(t, receipt.AlignCenter, header.Columns[1].Alignment)
+	assert.Equal(t, receipt.AlignRight, header.Columns[2].Alignment)
+
+	row := req.Receipt[2].(receipt.Columns)
+	assert.False(t, row.Bold)
+	assert.Equal(t, "Bagel", row.Columns[0].Content, "formatting is dropped in tables")
+	assert.Equal(t, "Bagel                                   1  $3.50", row.Layout())
+}
+
+func TestMarkdownToReceipt_TableTooWide(t *testing.T) {
+	tableRow := func(cells []string) string {
+		return "| " + strings.Join(cells, " | ") + " |"
+	}
+	cells, sep := make([]string, 30), make([]string, 30)
+	for i := range cells {
+		cells[i], sep[i] = fmt.Sprintf("c%d", i+1), "---"
+	}
+
+	// only 24 columns of one character fit on 48, the rest are merged into the last
+	req, err := markdownToReceipt(tableRow(cells) + "\n" + tableRow(sep))
+	assert.NoError(t, err)
+	header := req.Receipt[0].(receipt.Columns)
+	assert.Len(t, header.Columns, 24)
+	assert.Equal(t, "c24 c25 c26 c27 c28 c29 c30", header.Columns[23].Content)
+
+	// a long first column with many short ones, which stay 1 wide, still fits
+	cells, sep = append([]string{strings.Repeat("x", 200)}, cells[:19]...), sep[:20]
+	req, err = markdownToReceipt(tableRow(cells) + "\n" + tableRow(sep))
+	assert.NoError(t, err)
+	for _, item := range req.Receipt {
+		widths := item.(receipt.Columns).Widths()
+		total := len(widths) - 1
+		for _, w := range widths {
+			assert.GreaterOrEqual(t, w, 1)
+			total += w
+		}
+		assert.Equal(t, 48, total)
+	}
+}
+
+func TestMarkdownToReceipt_Images(t *testing.T) {
+	assetStore = NewAssetStore(t.TempDir())
+
+	req, err := markdownToReceipt("![logo](" + testPNG(10, 10) + ")")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, req.Receipt[0].(receipt.Image).Img.Bounds().Dx())
+
+	_, err = markdownToReceipt("text\n\n![logo](missing)")
+	assert.ErrorContains(t, err, "line 3")
+	assert.ErrorIs(t, err, errAssetNotFound)
+}
+
+func TestParseInline(t *testing.T) {
+	assert.Equal(t, []textRun{{text: "see "}, {text: "docs", underline: true}, {text: " at x (https://x.io) and code, 2 * 3"}},
+		parseInline("see *docs* at [x](https://x.io) and `code`, 2 * 3"))
+}
+
+func TestWrapRuns_LongWord(t *testing.T) {
+	lines := wrapRuns([]textRun{{text: "a abcdefghij"}}, 4, "", "")
+	assert.Equal(t, [][]textRun{{{text: "a"}}, {{text: "abcd"}}, {{text: "efgh"}}, {{text: "ij"}}}, lines)
+}
+
+func TestPrintMarkdown(t *testing.T) {
+	router := setupTestRouter()
+	w := doRequest(router, "POST", "/print/markdown", "# Notes\n\nRestock **oat milk**")
+	assert.Equal(t, 200, w.Code)
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/gin-gonic/gin"
+)
+
+var errNVGraphicNotLoaded = errors.New("no NV graphic loaded with key")
+
+// NVGraphics tracks the images stored in the printer's NV graphics memory.
+// A copy of every image is kept in an AssetStore named by its key code,
+// so we know what is loaded and printers that only have FS q can be redefined.
+type NVGraphics struct {
+	store *AssetStore
+	// legacy uses FS q / FS p instead of GS ( L, FS q always replaces every stored image
+	legacy bool
+}
+
+func NewNVGraphics(dir string, legacy bool) *NVGraphics {
+	return &NVGraphics{store: NewAssetStore(dir), legacy: legacy}
+}
+
+var nvGraphics = NewNVGraphics("nv", false)
+
+func (g *NVGraphics) List() ([]AssetMeta, error) {
+	return g.store.List()
+}
+
+// index returns the FS p image number of key, images are numbered from 1 in key order
+func (g *NVGraphics) index(key string) (int, error) {
+	list, err := g.store.List()
+	if err != nil {
+		return 0, err
+	}
+	for i, meta := range list {
+		if meta.Name == key {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", errNVGraphicNotLoaded, key)
+}
+
+// Loaded returns an error if nothing was uploaded under key
+func (g *NVGraphics) Loaded(key string) error {
+	if err := receipt.CheckNVKey(key); err != nil {
+		return err
+	}
+	_, err := g.index(key)
+	return err
+}
+
+// Upload stores an already processed image in the printer under key
+func (g *NVGraphics) Upload(p *render.ESCPOS, key string, img image.Image, ditherMode string) (AssetMeta, error) {
+	if err := receipt.CheckNVKey(key); err != nil {
+		return AssetMeta{}, err
+	}
+
+	if g.legacy {
+		images, err := g.images(key)
+		if err != nil {
+			return AssetMeta{}, err
+		}
+		images[key] = img
+		if err := p.Raw(render.DefineNVBitImages(images)...); err != nil {
+			return AssetMeta{}, err
+		}
+	} else {
+		if err := p.Raw(render.DefineNVGraphic(key, img)...); err != nil {
+			return AssetMeta{}, err
+		}
+	}
+
+	return g.store.Save(key, img, ditherMode)
+}
+
+// Delete removes the image with key from the printer
+func (g *NVGraphics) Delete(p *render.ESCPOS, key string) error {
+	if err := g.Loaded(key); err != nil {
+		return err
+	}
+
+	if g.legacy {
+		images, err := g.images(key)
+		if err != nil {
+			return err
+		}
+		// FS q can't define zero images, the old one stays in the printer but is no longer tracked
+		if len(images) > 0 {
+			if err := p.Raw(render.DefineNVBitImages(images)...); err != nil {
+				return err
+			}
+		}
+	} else {
+		if err := p.Raw(0x1D, '(', 'L', 4, 0, 48, 66, key[0], key[1]); err != nil {
+			return err
+		}
+	}
+
+	return g.store.Delete(key)
+}
+
+// Print prints the NV image, with FS p and the image number of its key when the graphics are legacy
+func (g *NVGraphics) Print(p *render.ESCPOS, n receipt.NVImage) error {
+	if g.legacy {
+		number, err := g.index(n.Key)
+		if err != nil {
+			return err
+		}
+		return p.NVBitImage(number, n)
+	}
+	return p.NVImage(n)
+}
+
+// images loads every tracked image except skip
+func (g *NVGraphics) images(skip string) (map[string]image.Image, error) {
+	list, err := g.store.List()
+	if err != nil {
+		return nil, err
+	}
+	images := make(map[string]image.Image)
+	for _, meta := range list {
+		if meta.Name == skip {
+			continue
+		}
+		img, err := g.store.Load(meta.Name)
+		if err != nil {
+			return nil, err
+		}
+		images[meta.Name] = img
+	}
+	return images, nil
+}
+
+func nvErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errNVGraphicNotLoaded):
+		return 404
+	case errors.Is(err, receipt.ErrInvalidNVKey):
+		return 400
+	}
+	return assetErrorStatus(err)
+}
+
+func handleListNVGraphics(c *gin.Context) {
+	list, err := nvGraphics.List()
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"graphics": list})
+}
+
+// handlePutNVGraphic uploads an image to the printer's NV memory,
+// the body is an image item so both stored assets and image data work
+func handlePutNVGraphic(c *gin.Context) {
+	key := c.Param("key")
+	if err := receipt.CheckNVKey(key); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	var img receipt.Image
+	if err := bindItem(c, &img); err != nil {
+		c.JSON(400, errorResponse(err))
+		return
+	}
+
+	if !tryLockPrinter(c) {
+		return
+	}
+	defer printerMutex.Unlock()
+
+	p := c.MustGet("printer").(*render.ESCPOS)
+	meta, err := nvGraphics.Upload(p, key, img.Bitmap(), string(img.DitherMode))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, meta)
+}
+
+func handleDeleteNVGraphic(c *gin.Context) {
+	if !tryLockPrinter(c) {
+		return
+	}
+	defer printerMutex.Unlock()
+
+	p := c.MustGet("printer").(*render.ESCPOS)
+	if err := nvGraphics.Delete(p, c.Param("key")); err != nil {
+		c.JSON(nvErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"success": true})
+}
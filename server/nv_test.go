@@ -0,0 +1,74 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/codaea/simpleprint/internal/testutil"
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBufferPrinter() (*render.ESCPOS, *testutil.BufferDevice) {
+	dev := &testutil.BufferDevice{}
+	p, _ := render.NewESCPOS(dev)
+	return p, dev
+}
+
+func TestNVGraphics_UploadPrintDelete(t *testing.T) {
+	nv := NewNVGraphics(t.TempDir(), false)
+	p, dev := newBufferPrinter()
+
+	_, err := nv.Upload(p, "L1", testutil.Checkerboard(16, 16), "none")
+	assert.NoError(t, err)
+	assert.NoError(t, nv.Loaded("L1"))
+	assert.Error(t, nv.Loaded("L2"))
+
+	dev.Reset()
+	assert.NoError(t, nv.Print(p, receipt.NVImage{Key: "L1", DoubleWidth: true}))
+	assert.Equal(t, []byte{0x1B, 'a', 0, 0x1D, '(', 'L', 6, 0, 48, 69, 'L', '1', 2, 1}, dev.Bytes())
+
+	dev.Reset()
+	assert.NoError(t, nv.Delete(p, "L1"))
+	assert.Equal(t, []byte{0x1D, '(', 'L', 4, 0, 48, 66, 'L', '1'}, dev.Bytes())
+	assert.ErrorIs(t, nv.Loaded("L1"), errNVGraphicNotLoaded)
+}
+
+func TestNVGraphics_Legacy(t *testing.T) {
+	nv := NewNVGraphics(t.TempDir(), true)
+	p, dev := newBufferPrinter()
+
+	nv.Upload(p, "B2", testutil.Checkerboard(8, 8), "none")
+	nv.Upload(p, "A1", testutil.Checkerboard(8, 8), "none")
+
+	dev.Reset()
+	assert.NoError(t, nv.Print(p, receipt.NVImage{Key: "B2", DoubleHeight: true}))
+	assert.Equal(t, []byte{0x1B, 'a', 0, 0x1C, 'p', 2, 2}, dev.Bytes())
+}
+
+func TestHandlePrint_NVImage(t *testing.T) {
+	nvGraphics = NewNVGraphics(t.TempDir(), false)
+	router := setupTestRouter()
+
+	w := doRequest(router, "POST", "/print", `{"receipt": [{"type": "nv_image", "key": "L1"}]}`)
+	assert.Equal(t, 400, w.Code)
+
+	w = doRequest(router, "PUT", "/nv/L1", `{"data": "`+testPNG(64, 32)+`", "dither_mode": "floydsteinberg"}`)
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequest(router, "GET", "/nv", "")
+	assert.Contains(t, w.Body.String(), `"name":"L1"`)
+
+	w = doRequest(router, "POST", "/print", `{"receipt": [{"type": "nv_image", "key": "L1", "alignment": "center"}]}`)
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequest(router, "DELETE", "/nv/L1", "")
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequest(router, "PUT", "/nv/toolong", `{"data": "`+testPNG(8, 8)+`"}`)
+	assert.Equal(t, 400, w.Code)
+
+	w = doRequest(router, "PUT", "/nv/L2", `{"data": "`+testPNG(8, 8)+`", "dither-mode": "none"}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), `"path":"/dither-mode"`)
+}
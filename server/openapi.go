@@ -0,0 +1,238 @@
+package server
+
+import (
+	"reflect"
+
+	"github.com/codaea/simpleprint/receipt"
+)
+
+const openAPIRef = "#/components/schemas/"
+
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": openAPIRef + name}
+}
+
+// apiContent is a request or response body of schema in each of the media types
+func apiContent(schema any, mediaTypes ...string) map[string]any {
+	body := make(map[string]any)
+	for _, mediaType := range mediaTypes {
+		body[mediaType] = map[string]any{"schema": schema}
+	}
+	return body
+}
+
+func apiBody(required bool, schema any, mediaTypes ...string) map[string]any {
+	return map[string]any{"required": required, "content": apiContent(schema, mediaTypes...)}
+}
+
+func apiQuery(name, description string, schema map[string]any) map[string]any {
+	return map[string]any{"name": name, "in": "query", "description": description, "schema": schema}
+}
+
+func apiPathParam(name string) map[string]any {
+	return map[string]any{"name": name, "in": "path", "required": true, "schema": map[string]any{"type": "string"}}
+}
+
+var errorDescriptions = map[string]string{
+	"400": "Invalid request",
+	"404": "Not found",
+	"500": "Printer or storage error",
+	"503": "Printer is busy with another job",
+}
+
+// apiResponses is a 200 response with ok as its JSON body, and an error response for each of codes
+func apiResponses(ok any, codes ...string) map[string]any {
+	r := map[string]any{"200": map[string]any{"description": "OK", "content": apiContent(ok, "application/json")}}
+	for _, code := range codes {
+		r[code] = map[string]any{"description": errorDescriptions[code], "content": apiContent(schemaRef("Error"), "application/json")}
+	}
+	return r
+}
+
+func apiObject(properties map[string]any) map[string]any {
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func apiArray(schema any) map[string]any {
+	return map[string]any{"type": "array", "items": schema}
+}
+
+var (
+	apiString  = map[string]any{"type": "string"}
+	apiSuccess = apiObject(map[string]any{"success": map[string]any{"type": "boolean"}})
+)
+
+// openAPISpec describes every endpoint, the schemas of the bodies are generated from the Go types
+func openAPISpec() map[string]any {
+	g := newSchemaGenerator(openAPIRef)
+	for _, t := range []reflect.Type{
+		reflect.TypeOf(receipt.Request{}),
+		reflect.TypeOf(receipt.Drawer{}),
+		reflect.TypeOf(receipt.Image{}),
+		reflect.TypeOf(AssetMeta{}),
+		reflect.TypeOf(TemplateVersion{}),
+		reflect.TypeOf(TemplateInfo{}),
+		reflect.TypeOf(receipt.FieldError{}),
+		reflect.TypeOf(receipt.ValidationResult{}),
+	} {
+		g.define(t)
+	}
+	g.defs["Error"] = apiObject(map[string]any{
+		"error":   apiString,
+		"message": apiString,
+		"errors":  apiArray(schemaRef("FieldError")),
+	})
+
+	alignment := g.typeSchema(reflect.TypeOf(receipt.AlignmentType("")))
+	version := apiQuery("version", "Version of the template, the latest if not set", map[string]any{"type": "integer", "minimum": 1})
+
+	paths := map[string]any{
+		"/print": map[string]any{"post": map[string]any{
+			"summary":     "Print a receipt",
+			"requestBody": apiBody(true, schemaRef("PrintRequest"), "application/json", "application/yaml", "application/toml"),
+			"responses":   apiResponses(apiSuccess, "400", "500", "503"),
+		}},
+		"/validate": map[string]any{"post": map[string]any{
+			"summary":     "Check a receipt without printing it",
+			"requestBody": apiBody(true, schemaRef("PrintRequest"), "application/json", "application/yaml", "application/toml"),
+			"responses":   apiResponses(schemaRef("ValidationResult")),
+		}},
+		"/print/image": map[string]any{"post": map[string]any{
+			"summary": "Print an uploaded image file",
+			"requestBody": apiBody(true, apiObject(map[string]any{
+				"file":        map[string]any{"type": "string", "format": "binary"},
+				"alignment":   alignment,
+				"width":       map[string]any{"type": "integer", "minimum": 0},
+				"dither_mode": map[string]any{"type": "string", "enum": []any{"none", "floydsteinberg"}},
+			}), "multipart/form-data"),
+			"responses": apiResponses(apiSuccess, "400", "500", "503"),
+		}},
+		"/print/markdown": map[string]any{"post": map[string]any{
+			"summary":     "Print a markdown document",
+			"requestBody": apiBody(true, apiString, "text/markdown"),
+			"responses":   apiResponses(apiSuccess, "400", "500", "503"),
+		}},
+		"/print/html": map[string]any{"post": map[string]any{
+			"summary": "Print an HTML document",
+			"parameters": []any{
+				apiQuery("mode", "text prints with the printer's fonts, raster draws the document as an image", map[string]any{"type": "string", "enum": []any{"text", "raster"}}),
+			},
+			"requestBody": apiBody(true, apiString, "text/html"),
+			"responses":   apiResponses(apiSuccess, "400", "500", "503"),
+		}},
+		"/print/text": map[string]any{"post": map[string]any{
+			"summary": "Print plain text",
+			"parameters": []any{
+				apiQuery("font", "Font of the text", g.typeSchema(reflect.TypeOf(receipt.FontType("")))),
+				apiQuery("size", "Font size multiplier", map[string]any{"type": "integer", "minimum": 1, "maximum": receipt.MaxFontSize}),
+				apiQuery("alignment", "Alignment of the text", alignment),
+				apiQuery("markup", "Print **bold**, __underline__ and --- separators", map[string]any{"type": "boolean"}),
+			},
+			"requestBody": apiBody(true, apiString, "text/plain"),
+			"responses":   apiResponses(apiSuccess, "400", "500", "503"),
+		}},
+		"/print/self-test": map[string]any{"post": map[string]any{
+			"summary":   "Print the self-test page with every font, code page, barcode type, QR size and dither mode",
+			"responses": apiResponses(apiSuccess, "400", "500", "503"),
+		}},
+		"/drawer/open": map[string]any{"post": map[string]any{
+			"summary":     "Open the cash drawer",
+			"requestBody": apiBody(false, schemaRef("Drawer"), "application/json"),
+			"responses":   apiResponses(apiSuccess, "400", "500", "503"),
+		}},
+		"/assets": map[string]any{"get": map[string]any{
+			"summary":   "List the stored assets",
+			"responses": apiResponses(apiObject(map[string]any{"assets": apiArray(schemaRef("AssetMeta"))}), "500"),
+		}},
+		"/assets/{name}": map[string]any{
+			"parameters": []any{apiPathParam("name")},
+			"get": map[string]any{
+				"summary": "Get an asset as the PNG that is printed",
+				"responses": map[string]any{
+					"200": map[string]any{"description": "OK", "content": apiContent(map[string]any{"type": "string", "format": "binary"}, "image/png")},
+					"404": map[string]any{"description": errorDescriptions["404"], "content": apiContent(schemaRef("Error"), "application/json")},
+				},
+			},
+			"put": map[string]any{
+				"summary":     "Store an image as an asset",
+				"requestBody": apiBody(true, schemaRef("Image"), "application/json"),
+				"responses":   apiResponses(schemaRef("AssetMeta"), "400", "500"),
+			},
+			"delete": map[string]any{
+				"summary":   "Delete an asset",
+				"responses": apiResponses(apiSuccess, "400", "404", "500"),
+			},
+		},
+		"/templates": map[string]any{"get": map[string]any{
+			"summary":   "List the templates and their versions",
+			"responses": apiResponses(apiObject(map[string]any{"templates": apiArray(schemaRef("TemplateInfo"))}), "500"),
+		}},
+		"/templates/{name}": map[string]any{
+			"parameters": []any{apiPathParam("name")},
+			"get": map[string]any{
+				"summary":    "Get the text of a template",
+				"parameters": []any{version},
+				"responses": apiResponses(apiObject(map[string]any{
+					"name":       apiString,
+					"version":    map[string]any{"type": "integer"},
+					"created_at": map[string]any{"type": "string", "format": "date-time"},
+					"template":   apiString,
+					"versions":   apiArray(schemaRef("TemplateVersion")),
+				}), "400", "404", "500"),
+			},
+			"put": map[string]any{
+				"summary":     "Save a new version of a template",
+				"requestBody": apiBody(true, apiString, "text/plain"),
+				"responses":   apiResponses(schemaRef("TemplateVersion"), "400", "500"),
+			},
+			"delete": map[string]any{
+				"summary":   "Delete a template with all its versions",
+				"responses": apiResponses(apiSuccess, "400", "404", "500"),
+			},
+		},
+		"/templates/{name}/print": map[string]any{
+			"parameters": []any{apiPathParam("name")},
+			"post": map[string]any{
+				"summary":     "Render a template with the data in the body and print it",
+				"parameters":  []any{version},
+				"requestBody": apiBody(false, map[string]any{"type": "object"}, "application/json"),
+				"responses":   apiResponses(apiSuccess, "400", "404", "500", "503"),
+			},
+		},
+		"/nv": map[string]any{"get": map[string]any{
+			"summary":   "List the images stored in the printer's NV memory",
+			"responses": apiResponses(apiObject(map[string]any{"graphics": apiArray(schemaRef("AssetMeta"))}), "500"),
+		}},
+		"/nv/{key}": map[string]any{
+			"parameters": []any{apiPathParam("key")},
+			"put": map[string]any{
+				"summary":     "Upload an image to the printer's NV memory",
+				"requestBody": apiBody(true, schemaRef("Image"), "application/json"),
+				"responses":   apiResponses(schemaRef("AssetMeta"), "400", "500", "503"),
+			},
+			"delete": map[string]any{
+				"summary":   "Delete an image from the printer's NV memory",
+				"responses": apiResponses(apiSuccess, "400", "404", "500", "503"),
+			},
+		},
+		"/openapi.json": map[string]any{"get": map[string]any{
+			"summary":   "This document",
+			"responses": apiResponses(map[string]any{"type": "object"}),
+		}},
+		"/schema/receipt.json": map[string]any{"get": map[string]any{
+			"summary":   "JSON Schema of a print request",
+			"responses": apiResponses(map[string]any{"type": "object"}),
+		}},
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "SimplePrint",
+			"description": "Print receipts on ESC/POS thermal printers",
+			"version":     "1.0.0",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": g.defs},
+	}
+}
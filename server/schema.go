@@ -0,0 +1,222 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codaea/simpleprint/internal/jsonfield"
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/gin-gonic/gin"
+)
+
+// schemaEnums are the values accepted by the string types that are enums
+func schemaEnums() map[reflect.Type][]any {
+	barcodes := make([]string, 0, len(receipt.BarcodeTypes))
+	for _, t := range receipt.BarcodeTypes {
+		barcodes = append(barcodes, string(t))
+	}
+	sort.Strings(barcodes)
+	codePages := make([]string, 0, len(receipt.CodePages))
+	for _, c := range receipt.CodePages {
+		codePages = append(codePages, string(c))
+	}
+
+	return map[reflect.Type][]any{
+		reflect.TypeOf(receipt.AlignmentType("")):     {"left", "center", "right"},
+		reflect.TypeOf(receipt.FontType("")):          {"A", "B", "C"},
+		reflect.TypeOf(receipt.CutMode("")):           {"full", "partial", "none"},
+		reflect.TypeOf(receipt.HRIPosition("")):       {"none", "above", "below", "both"},
+		reflect.TypeOf(receipt.QRErrorCorrection("")): {"L", "M", "Q", "H"},
+		reflect.TypeOf(receipt.RenderMode("")):        {"native", "raster"},
+		reflect.TypeOf(receipt.BeepMethod("")):        {"esc_b", "esc_paren_a"},
+		reflect.TypeOf(receipt.DitherMode("")):        {"none", "floydsteinberg"},
+		reflect.TypeOf(receipt.BarcodeType("")):       toAnySlice(barcodes),
+		reflect.TypeOf(receipt.CodePage("")):          toAnySlice(codePages),
+	}
+}
+
+func toAnySlice(values []string) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+// schemaGenerator builds JSON Schemas from Go types. Struct types become definitions,
+// referenced as ref + the type name, so the same generator serves the schema document and OpenAPI.
+// Constraints come from the schema struct tag, like `schema:"minimum=1,maximum=8"` or `schema:"enum=2|5"`.
+type schemaGenerator struct {
+	ref   string
+	defs  map[string]any
+	enums map[reflect.Type][]any
+	// itemTypes are the type field of every receipt item type
+	itemTypes map[reflect.Type]string
+}
+
+func newSchemaGenerator(ref string) *schemaGenerator {
+	g := &schemaGenerator{
+		ref:       ref,
+		defs:      make(map[string]any),
+		enums:     schemaEnums(),
+		itemTypes: make(map[reflect.Type]string),
+	}
+	for name, t := range receipt.ItemTypes {
+		g.itemTypes[t] = name
+	}
+	return g
+}
+
+// define adds struct t to the definitions and returns a reference to it
+func (g *schemaGenerator) define(t reflect.Type) map[string]any {
+	name := schemaName(t)
+	if _, ok := g.defs[name]; !ok {
+		g.defs[name] = nil // stops recursion
+		g.defs[name] = g.structSchema(t)
+	}
+	return map[string]any{"$ref": g.ref + name}
+}
+
+// schemaName is the definition name of struct t, the print request keeps the name it had before it moved to the receipt package
+func schemaName(t reflect.Type) string {
+	if t == reflect.TypeOf(receipt.Request{}) {
+		return "PrintRequest"
+	}
+	return t.Name()
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	for name, field := range jsonfield.Fields(t) {
+		properties[name] = g.fieldSchema(field)
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if itemType, ok := g.itemTypes[t]; ok {
+		properties["type"] = map[string]any{"const": itemType}
+		schema["required"] = []string{"type"}
+	}
+	return schema
+}
+
+func (g *schemaGenerator) fieldSchema(field reflect.StructField) map[string]any {
+	schema := g.typeSchema(field.Type)
+	tag := field.Tag.Get("schema")
+	if tag == "" {
+		return schema
+	}
+
+	for _, constraint := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(constraint, "=")
+		switch key {
+		case "enum":
+			var values []any
+			for _, v := range strings.Split(value, "|") {
+				values = append(values, schemaValue(v))
+			}
+			schema["enum"] = values
+		case "pattern":
+			schema[key] = value
+		default:
+			schema[key] = schemaValue(value)
+		}
+	}
+	return schema
+}
+
+// schemaValue parses a number in a schema tag, anything else stays a string
+func schemaValue(s string) any {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return s
+}
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) map[string]any {
+	if values, ok := g.enums[t]; ok {
+		return map[string]any{"type": "string", "enum": values}
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if t == reflect.TypeOf((*receipt.Item)(nil)).Elem() {
+		return g.receiptItemSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		return g.define(t)
+	}
+	return map[string]any{}
+}
+
+// receiptItemSchema is any one of the receipt item types, told apart by their type field
+func (g *schemaGenerator) receiptItemSchema() map[string]any {
+	names := make([]string, 0, len(receipt.ItemTypes))
+	for name := range receipt.ItemTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]any, len(names))
+	for i, name := range names {
+		items[i] = g.define(receipt.ItemTypes[name])
+	}
+	return map[string]any{"oneOf": items}
+}
+
+// receiptSchema is the JSON Schema of a print request, with a definition for each receipt item
+func receiptSchema() map[string]any {
+	g := newSchemaGenerator("#/$defs/")
+	schema := g.structSchema(reflect.TypeOf(receipt.Request{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "PrintRequest"
+	schema["$defs"] = g.defs
+	return schema
+}
+
+// the documents are generated once, the types can't change while running
+var (
+	receiptSchemaJSON = sync.OnceValue(func() []byte { return mustMarshal(receiptSchema()) })
+	openAPIJSON       = sync.OnceValue(func() []byte { return mustMarshal(openAPISpec()) })
+)
+
+func mustMarshal(v any) []byte {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func handleReceiptSchema(c *gin.Context) {
+	c.Data(200, "application/schema+json", receiptSchemaJSON())
+}
+
+func handleOpenAPI(c *gin.Context) {
+	c.Data(200, "application/json", openAPIJSON())
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/gin-gonic/gin"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// testExamples returns every string literal in the tests of this package and the library packages that is a JSON object
+func testExamples(t *testing.T) []map[string]any {
+	var files []string
+	for _, pattern := range []string{"*_test.go", "../receipt/*_test.go", "../render/*_test.go"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, matches...)
+	}
+
+	var examples []map[string]any
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			lit, ok := n.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			s, err := strconv.Unquote(lit.Value)
+			if err != nil || !strings.HasPrefix(strings.TrimSpace(s), "{") {
+				return true
+			}
+			var example map[string]any
+			if json.Unmarshal([]byte(s), &example) == nil {
+				examples = append(examples, example)
+			}
+			return true
+		})
+	}
+	return examples
+}
+
+func compileSchema(t *testing.T, ref string) *jsonschema.Schema {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("receipt.json", bytes.NewReader(receiptSchemaJSON())); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := compiler.Compile("receipt.json" + ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// printedBodies are the JSON bodies of every request printed by the test router
+var printedBodies struct {
+	sync.Mutex
+	bodies [][]byte
+}
+
+// recordPrintedBodies is a middleware of the test router that keeps the body of every successful JSON print
+func recordPrintedBodies(c *gin.Context) {
+	if c.Request.URL.Path != "/print" || c.ContentType() != "application/json" {
+		c.Next()
+		return
+	}
+	body, _ := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Next()
+	if c.Writer.Status() == 200 {
+		printedBodies.Lock()
+		printedBodies.bodies = append(printedBodies.bodies, body)
+		printedBodies.Unlock()
+	}
+}
+
+// TestMain checks every request the tests printed against the schema once they have all run,
+// which covers the requests built in Go as well as the JSON literals
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("receipt.json", bytes.NewReader(receiptSchemaJSON())); err != nil {
+		panic(err)
+	}
+	schema := compiler.MustCompile("receipt.json")
+	for _, body := range printedBodies.bodies {
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			continue
+		}
+		if err := schema.Validate(v); err != nil {
+			fmt.Printf("schema rejects a printed test request: %v\n%s\n", err, body)
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+// TestReceiptSchema_AcceptsTestExamples checks the schema against every request and item in the tests
+// that the server accepts, so the schema can't drift from the Go types
+func TestReceiptSchema_AcceptsTestExamples(t *testing.T) {
+	requestSchema := compileSchema(t, "")
+
+	requests, items := 0, 0
+	for _, example := range testExamples(t) {
+		data, _ := json.Marshal(example)
+
+		if _, ok := example["receipt"]; ok {
+			var req receipt.Request
+			if json.Unmarshal(data, &req) != nil {
+				continue
+			}
+			requests++
+			assert.NoError(t, requestSchema.Validate(example), "%s", data)
+			continue
+		}
+
+		itemType, _ := example["type"].(string)
+		goType, ok := receipt.ItemTypes[itemType]
+		if !ok {
+			continue
+		}
+		var req receipt.Request
+		if json.Unmarshal([]byte(`{"receipt": [`+string(data)+`]}`), &req) != nil {
+			continue
+		}
+		items++
+		assert.NoError(t, compileSchema(t, "#/$defs/"+goType.Name()).Validate(example), "%s", data)
+	}
+
+	// the scan finding nothing would pass silently
+	assert.Greater(t, requests, 10)
+	assert.Greater(t, items, 10)
+}
+
+func TestReceiptSchema_RejectsInvalid(t *testing.T) {
+	schema := compileSchema(t, "")
+
+	for _, body := range []string{
+		`{"receipt": [{"type": "line", "content": "x", "font-size": 2}]}`,
+		`{"receipt": [{"type": "line", "content": "x", "font_size": 9}]}`,
+		`{"receipt": [{"type": "line", "content": "x", "font": "D"}]}`,
+		`{"receipt": [{"type": "qr", "code": "x", "size": 17}]}`,
+		`{"receipt": [{"type": "feed", "lines": 256}]}`,
+		`{"receipt": [{"type": "sparkles"}]}`,
+		`{"receipt": [{"content": "no type"}]}`,
+		`{"receipt": [], "cuts": "none"}`,
+	} {
+		var v any
+		assert.NoError(t, json.Unmarshal([]byte(body), &v))
+		assert.Error(t, schema.Validate(v), body)
+	}
+}
+
+func TestOpenAPI_CoversEveryRoute(t *testing.T) {
+	router := setupTestRouter()
+	w := doRequest(router, "GET", "/openapi.json", "")
+	assert.Equal(t, 200, w.Code)
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, "3.1.0", spec.OpenAPI)
+
+	for _, route := range router.Routes() {
+		parts := strings.Split(route.Path, "/")
+		for i, part := range parts {
+			if name, ok := strings.CutPrefix(part, ":"); ok {
+				parts[i] = "{" + name + "}"
+			}
+		}
+		path := strings.Join(parts, "/")
+		assert.Contains(t, spec.Paths[path], strings.ToLower(route.Method), "%s %s is not documented", route.Method, path)
+	}
+
+	// every reference points at a schema that exists
+	refs := regexp.MustCompile(`"\$ref":\s*"#/components/schemas/([A-Za-z]+)"`).FindAllStringSubmatch(w.Body.String(), -1)
+	var doc struct {
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	for _, ref := range refs {
+		assert.Contains(t, doc.Components.Schemas, ref[1])
+	}
+	assert.Contains(t, doc.Components.Schemas, "QRCode")
+}
+
+func TestHandleReceiptSchema(t *testing.T) {
+	w := doRequest(setupTestRouter(), "GET", "/schema/receipt.json", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/schema+json", w.Header().Get("Content-Type"))
+
+	var schema map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &schema))
+	assert.Equal(t, "https://json-schema.org/draft/2020-12/schema", schema["$schema"])
+	assert.Len(t, schema["$defs"], len(receipt.ItemTypes)+1) // the items and Column
+}
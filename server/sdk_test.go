@@ -0,0 +1,27 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSDK_BuilderRequestPrints(t *testing.T) {
+	req := receipt.New().
+		Line("Coffee Shop", receipt.Bold, receipt.Center, receipt.Size(2)).
+		Columns([]receipt.Column{receipt.Col("Latte", 0, receipt.AlignLeft), receipt.Col("$4.50", 8, receipt.AlignRight)}).
+		Feed(1).
+		QR("https://example.com/r/123", receipt.Center).
+		Barcode("123456789012", receipt.BarcodeUPCA).
+		Image(testPNG(8, 8)).
+		Add(receipt.PDF417{Code: "hello"}, receipt.Beep{Count: 1}).
+		FinalCut(receipt.CutPartial).
+		Build()
+	body, err := json.Marshal(req)
+	assert.NoError(t, err)
+
+	w := doRequest(setupTestRouter(), "POST", "/print", string(body))
+	assert.Equal(t, 200, w.Code, w.Body.String())
+}
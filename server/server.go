@@ -0,0 +1,96 @@
+// Package server is the HTTP API of SimplePrint. Requests are decoded with the receipt package
+// and printed with render, assets, templates and NV graphics are stored on disk.
+package server
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	// items that refer to assets and NV graphics are checked against the stores when they are decoded
+	receipt.LoadAsset = func(name string) (image.Image, error) {
+		return assetStore.Load(name)
+	}
+	receipt.CheckNVGraphic = func(key string) error {
+		return nvGraphics.Loaded(key)
+	}
+}
+
+// Configure loads the printer's profile from the config file and the environment and sets up the
+// paper, fonts and storage for it. The config file is CONFIG_FILE, or simpleprint.yaml, .yml, .toml or .json
+// in the working directory, and PRINTER_PROFILE picks one of its printers. PRINTER_PATH, PAPER_WIDTH and
+// CUT_FEED_LINES override the profile, and ASSETS_PATH, TEMPLATES_PATH, NV_PATH and NV_LEGACY set the storage.
+// Every problem with the settings is returned together.
+func Configure() error {
+	name, p, err := loadProfile()
+	if err != nil {
+		return err
+	}
+	errs := []error{p.overrideFromEnv()}
+	if err := p.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid settings for printer %s:\n%w", name, err)
+	}
+	profileName, profile = name, p
+	applyProfile(p)
+
+	if assetsPath, found := os.LookupEnv("ASSETS_PATH"); found {
+		assetStore = NewAssetStore(assetsPath)
+	}
+	if templatesPath, found := os.LookupEnv("TEMPLATES_PATH"); found {
+		templateStore = NewTemplateStore(templatesPath)
+	}
+	if nvPath, found := os.LookupEnv("NV_PATH"); found {
+		nvGraphics = NewNVGraphics(nvPath, false)
+	}
+	// printers without GS ( L only have the older FS q NV bit images
+	nvGraphics.legacy = os.Getenv("NV_LEGACY") == "true"
+	return nil
+}
+
+// UsePrinter is middleware that gives the handlers the printer
+func UsePrinter(p *render.ESCPOS) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("printer", p)
+		c.Next()
+	}
+}
+
+// Routes registers every endpoint, the printer must be set with UsePrinter first
+func Routes(r gin.IRoutes) {
+	r.POST("/print", handlePrint)
+	r.POST("/validate", handleValidate)
+	r.POST("/print/image", handlePrintImage)
+	r.POST("/print/markdown", handlePrintMarkdown)
+	r.POST("/print/html", handlePrintHTML)
+	r.POST("/print/text", handlePrintText)
+	r.POST("/print/self-test", handlePrintSelfTest)
+	r.POST("/drawer/open", handleOpenDrawer)
+
+	r.GET("/assets", handleListAssets)
+	r.GET("/assets/:name", handleGetAsset)
+	r.PUT("/assets/:name", handlePutAsset)
+	r.DELETE("/assets/:name", handleDeleteAsset)
+
+	r.GET("/templates", handleListTemplates)
+	r.GET("/templates/:name", handleGetTemplate)
+	r.PUT("/templates/:name", handlePutTemplate)
+	r.DELETE("/templates/:name", handleDeleteTemplate)
+	r.POST("/templates/:name/print", handlePrintTemplate)
+
+	r.GET("/nv", handleListNVGraphics)
+	r.PUT("/nv/:key", handlePutNVGraphic)
+	r.DELETE("/nv/:key", handleDeleteNVGraphic)
+
+	r.GET("/openapi.json", handleOpenAPI)
+	r.GET("/schema/receipt.json", handleReceiptSchema)
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlePrint_2DCodes(t *testing.T) {
+	router := setupTestRouter()
+
+	w := doRequest(router, "POST", "/print", `
+	{
+		"receipt": [
+			{"type": "pdf417", "code": "M1DOE/JOHN EABC123 LHRJFKBA 0117 123Y026A0001 100", "error_correction": 2},
+			{"type": "datamatrix", "code": "(01)09501101530003(17)250101", "size": 4},
+			{"type": "aztec", "code": "https://example.com/ticket/42", "render": "raster"}
+		]
+	}`)
+	assert.Equal(t, 200, w.Code)
+}
@@ -0,0 +1,496 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errTemplateNotFound    = errors.New("template not found")
+	errInvalidTemplateName = errors.New("invalid template name")
+	errInvalidTemplate     = errors.New("invalid template")
+)
+
+// TemplateStore keeps receipt templates on disk.
+// A template is a print request written as a Go text/template,
+// rendering it with a data object gives the JSON of the request to print.
+// Every save adds a version, stored as <name>/<version>.tmpl and listed in <name>/versions.json.
+type TemplateStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// TemplateVersion describes one saved version of a template
+type TemplateVersion struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TemplateInfo describes a template and all of its versions, oldest first
+type TemplateInfo struct {
+	Name     string            `json:"name"`
+	Versions []TemplateVersion `json:"versions"`
+}
+
+func NewTemplateStore(dir string) *TemplateStore {
+	return &TemplateStore{dir: dir}
+}
+
+var templateStore = NewTemplateStore("templates")
+
+func validateTemplateName(name string) error {
+	if !assetNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: %s. Must be 1-64 letters, digits, - or _", errInvalidTemplateName, name)
+	}
+	return nil
+}
+
+func (s *TemplateStore) path(name string, version int) string {
+	return filepath.Join(s.dir, name, strconv.Itoa(version)+".tmpl")
+}
+
+func (s *TemplateStore) versionsPath(name string) string {
+	return filepath.Join(s.dir, name, "versions.json")
+}
+
+// versions reads the version list of a template, the caller must hold mu
+func (s *TemplateStore) versions(name string) ([]TemplateVersion, error) {
+	data, err := os.ReadFile(s.versionsPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", errTemplateNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var versions []TemplateVersion
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("error reading versions of template %s: %v", name, err)
+	}
+	return versions, nil
+}
+
+// Save validates the template text and stores it as a new version of name.
+// The template is rendered with no data and must give a valid print request,
+// so templates have to cope with missing values, which the helpers do.
+func (s *TemplateStore) Save(name, text string) (TemplateVersion, error) {
+	if err := validateTemplateName(name); err != nil {
+		return TemplateVersion{}, err
+	}
+	tmpl, err := parseTemplate(name, text)
+	if err != nil {
+		return TemplateVersion{}, err
+	}
+	if _, err := renderTemplate(tmpl, nil); err != nil {
+		return TemplateVersion{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions, err := s.versions(name)
+	if err != nil && !errors.Is(err, errTemplateNotFound) {
+		return TemplateVersion{}, err
+	}
+	version := TemplateVersion{Version: 1, CreatedAt: time.Now().UTC()}
+	if len(versions) > 0 {
+		version.Version = versions[len(versions)-1].Version + 1
+	}
+	versions = append(versions, version)
+	versionsJSON, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return TemplateVersion{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.dir, name), 0o755); err != nil {
+		return TemplateVersion{}, err
+	}
+	if err := os.WriteFile(s.path(name, version.Version), []byte(text), 0o644); err != nil {
+		return TemplateVersion{}, err
+	}
+	if err := os.WriteFile(s.versionsPath(name), versionsJSON, 0o644); err != nil {
+		return TemplateVersion{}, err
+	}
+	return version, nil
+}
+
+// Versions returns every version of name, oldest first
+func (s *TemplateStore) Versions(name string) ([]TemplateVersion, error) {
+	if err := validateTemplateName(name); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.versions(name)
+}
+
+// Read returns the text of a version of name, version 0 is the latest
+func (s *TemplateStore) Read(name string, version int) (string, TemplateVersion, error) {
+	if err := validateTemplateName(name); err != nil {
+		return "", TemplateVersion{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions, err := s.versions(name)
+	if err != nil {
+		return "", TemplateVersion{}, err
+	}
+	var found *TemplateVersion
+	for i := range versions {
+		if version == 0 || versions[i].Version == version {
+			found = &versions[i]
+		}
+	}
+	if found == nil {
+		return "", TemplateVersion{}, fmt.Errorf("%w: %s version %d", errTemplateNotFound, name, version)
+	}
+
+	text, err := os.ReadFile(s.path(name, found.Version))
+	if err != nil {
+		return "", TemplateVersion{}, err
+	}
+	return string(text), *found, nil
+}
+
+// Load returns a version of the template stored under name parsed, version 0 is the latest
+func (s *TemplateStore) Load(name string, version int) (*template.Template, error) {
+	text, _, err := s.Read(name, version)
+	if err != nil {
+		return nil, err
+	}
+	return parseTemplate(name, text)
+}
+
+// Delete removes a template with all of its versions
+func (s *TemplateStore) Delete(name string) error {
+	if err := validateTemplateName(name); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.versions(name); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(s.dir, name))
+}
+
+// List returns every stored template with its versions sorted by name
+func (s *TemplateStore) List() ([]TemplateInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []TemplateInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	list := []TemplateInfo{}
+	for _, entry := range entries {
+		if !entry.IsDir() || validateTemplateName(entry.Name()) != nil {
+			continue
+		}
+		versions, err := s.versions(entry.Name())
+		if errors.Is(err, errTemplateNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, TemplateInfo{Name: entry.Name(), Versions: versions})
+	}
+	return list, nil
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// renderTemplate executes tmpl with data and decodes the result as a print request
+func renderTemplate(tmpl *template.Template, data any) (receipt.Request, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return receipt.Request{}, fmt.Errorf("%w: %v", errInvalidTemplate, err)
+	}
+
+	var req receipt.Request
+	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
+		return receipt.Request{}, fmt.Errorf("%w: rendered template is not a valid print request: %w", errInvalidTemplate, err)
+	}
+	return req, nil
+}
+
+// templateFuncs are the helpers available in templates.
+// Values come from JSON, so numbers can be float64, json.Number or strings and any value can be missing.
+var templateFuncs = template.FuncMap{
+	"json":     templateJSON,
+	"number":   formatNumber,
+	"currency": formatCurrency,
+	"date":     formatDate,
+	"add":      func(a, b any) float64 { return toFloat(a) + toFloat(b) },
+	"sub":      func(a, b any) float64 { return toFloat(a) - toFloat(b) },
+	"mul":      func(a, b any) float64 { return toFloat(a) * toFloat(b) },
+	"upper":    func(v any) string { return strings.ToUpper(toString(v)) },
+	"lower":    func(v any) string { return strings.ToLower(toString(v)) },
+	"padLeft":  func(width int, v any) string { return pad(toString(v), width, true) },
+	"padRight": func(width int, v any) string { return pad(toString(v), width, false) },
+}
+
+// templateJSON quotes a value for use inside the JSON of the template, so data can't break the request
+func templateJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case json.Number:
+		f, _ := n.Float64()
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f
+	}
+	return 0
+}
+
+func toString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// formatNumber formats v with the given decimals and thousands separators, like 1,234.50
+func formatNumber(decimals int, v any) string {
+	f := toFloat(v)
+	s := strconv.FormatFloat(math.Abs(f), 'f', decimals, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	if frac != "" {
+		grouped.WriteString("." + frac)
+	}
+	if f < 0 && strings.Trim(grouped.String(), "0.,") != "" {
+		return "-" + grouped.String()
+	}
+	return grouped.String()
+}
+
+// formatCurrency formats v as an amount with 2 decimals after symbol, like $1,234.50 or -$3.00
+func formatCurrency(symbol string, v any) string {
+	s := formatNumber(2, v)
+	if negative, ok := strings.CutPrefix(s, "-"); ok {
+		return "-" + symbol + negative
+	}
+	return symbol + s
+}
+
+// formatDate formats an RFC 3339 date, a unix timestamp or "now" with a Go time layout
+func formatDate(layout string, v any) (string, error) {
+	var t time.Time
+	switch d := v.(type) {
+	case time.Time:
+		t = d
+	case string:
+		if d == "now" {
+			t = time.Now()
+			break
+		}
+		parsed, err := time.Parse(time.RFC3339, d)
+		if err != nil {
+			parsed, err = time.Parse(time.DateOnly, d)
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid date: %s. Must be RFC 3339 or YYYY-MM-DD", d)
+		}
+		t = parsed
+	case nil:
+		return "", nil
+	default:
+		t = time.Unix(int64(toFloat(d)), 0)
+	}
+	return t.Format(layout), nil
+}
+
+// pad pads s with spaces to width characters, on the left to right align it
+func pad(s string, width int, left bool) string {
+	n := width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+	if left {
+		return strings.Repeat(" ", n) + s
+	}
+	return s + strings.Repeat(" ", n)
+}
+
+func templateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errTemplateNotFound):
+		return 404
+	case errors.Is(err, errInvalidTemplateName), errors.Is(err, errInvalidTemplate):
+		return 400
+	}
+	return 500
+}
+
+// templateVersion reads the version query parameter, 0 for the latest version
+func templateVersion(c *gin.Context) (int, error) {
+	v := c.Query("version")
+	if v == "" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid version: %s. Must be a number from 1", v)
+	}
+	return version, nil
+}
+
+func handleListTemplates(c *gin.Context) {
+	list, err := templateStore.List()
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"templates": list})
+}
+
+// handleGetTemplate returns the text of the latest version, or the one in ?version=, with the version history
+func handleGetTemplate(c *gin.Context) {
+	version, err := templateVersion(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := c.Param("name")
+	text, found, err := templateStore.Read(name, version)
+	if err != nil {
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	versions, err := templateStore.Versions(name)
+	if err != nil {
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"name":       name,
+		"version":    found.Version,
+		"created_at": found.CreatedAt,
+		"template":   text,
+		"versions":   versions,
+	})
+}
+
+// handlePutTemplate stores the request body as a new version of the template
+func handlePutTemplate(c *gin.Context) {
+	text, err := c.GetRawData()
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	version, err := templateStore.Save(c.Param("name"), string(text))
+	if err != nil {
+		c.JSON(templateErrorStatus(err), errorResponse(err))
+		return
+	}
+	c.JSON(200, version)
+}
+
+func handleDeleteTemplate(c *gin.Context) {
+	if err := templateStore.Delete(c.Param("name")); err != nil {
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"success": true})
+}
+
+// handlePrintTemplate renders a template with the data object in the body and prints it,
+// ?version= prints an older version
+func handlePrintTemplate(c *gin.Context) {
+	version, err := templateVersion(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	tmpl, err := templateStore.Load(c.Param("name"), version)
+	if err != nil {
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	var data map[string]any
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &data); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	req, err := renderTemplate(tmpl, data)
+	if err != nil {
+		c.JSON(templateErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	if !tryLockPrinter(c) {
+		return
+	}
+	defer printerMutex.Unlock()
+
+	p := c.MustGet("printer").(*render.ESCPOS)
+	if err := Print(p, req); err != nil {
+		c.JSON(printErrorStatus(err), errorResponse(err))
+		return
+	}
+	c.JSON(200, gin.H{"success": true})
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/stretchr/testify/assert"
+)
+
+const orderTemplate = `{
+	"receipt": [
+		{"type": "line", "content": {{json (upper .store)}}, "alignment": "center"},
+		{{- range .items}}
+		{"type": "line", "content": {{json (printf "%s x%v %s" (padRight 16 .name) .qty (mul .qty .price | currency "$"))}}},
+		{{- end}}
+		{{- if .paid}}
+		{"type": "line", "content": "PAID"},
+		{{- end}}
+		{"type": "line", "content": {{json (date "02/01/2006" .date)}}}
+	]
+}`
+
+func TestRenderTemplate(t *testing.T) {
+	tmpl, err := parseTemplate("order", orderTemplate)
+	assert.NoError(t, err)
+
+	req, err := renderTemplate(tmpl, map[string]any{
+		"store": "Corner \"Cafe\"",
+		"items": []any{
+			map[string]any{"name": "Coffee", "qty": 2.0, "price": 3.5},
+			map[string]any{"name": "Bagel", "qty": 1.0, "price": "1250"},
+		},
+		"date": "2024-03-05",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, req.Receipt, 4)
+	assert.Equal(t, `CORNER "CAFE"`, req.Receipt[0].(receipt.Line).Content)
+	assert.Equal(t, "Coffee           x2 $7.00", req.Receipt[1].(receipt.Line).Content)
+	assert.Equal(t, "Bagel            x1 $1,250.00", req.Receipt[2].(receipt.Line).Content)
+	assert.Equal(t, "05/03/2024", req.Receipt[3].(receipt.Line).Content, "unpaid orders skip the PAID line")
+}
+
+func TestRenderTemplate_InvalidOutput(t *testing.T) {
+	tmpl, err := parseTemplate("bad", `{"receipt": [{"type": "line", "alignment": {{json .align}}}]}`)
+	assert.NoError(t, err)
+
+	_, err = renderTemplate(tmpl, map[string]any{"align": "middle"})
+	assert.ErrorIs(t, err, errInvalidTemplate)
+	assert.Contains(t, err.Error(), "invalid alignment")
+}
+
+func TestTemplateFormatting(t *testing.T) {
+	assert.Equal(t, "1,234,567.9", formatNumber(1, 1234567.89))
+	assert.Equal(t, "0", formatNumber(0, nil))
+	assert.Equal(t, "-$3.00", formatCurrency("$", -3))
+	assert.Equal(t, "€0.00", formatCurrency("€", -0.001), "amounts rounding to zero aren't negative")
+	assert.Equal(t, "   42", pad("42", 5, true))
+
+	date, err := formatDate("2006-01-02 15:04", "2024-03-05T14:30:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-03-05 14:30", date)
+	_, err = formatDate("2006", "yesterday")
+	assert.Error(t, err)
+}
+
+func TestTemplates_SaveAndPrint(t *testing.T) {
+	templateStore = NewTemplateStore(t.TempDir())
+	router := setupTestRouter()
+
+	w := doRequest(router, "PUT", "/templates/order", orderTemplate)
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequest(router, "POST", "/templates/order/print", `{"store": "Cafe", "paid": true, "items": [{"name": "Tea", "qty": 1, "price": 2}], "date": "2024-03-05"}`)
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequest(router, "POST", "/templates/missing/print", `{}`)
+	assert.Equal(t, 404, w.Code)
+
+	w = doRequest(router, "PUT", "/templates/broken", `{"receipt": [{{range .items}]}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid template")
+}
+
+func TestTemplates_Versions(t *testing.T) {
+	templateStore = NewTemplateStore(t.TempDir())
+	router := setupTestRouter()
+
+	w := doRequest(router, "PUT", "/templates/note", `{"receipt": [{"type": "line", "content": "v1"}]}`)
+	assert.Equal(t, 200, w.Code)
+	w = doRequest(router, "PUT", "/templates/note", `{"receipt": [{"type": "line", "content": {{json .name}}, "alignment": "right"}]}`)
+	assert.Equal(t, 200, w.Code)
+	var saved TemplateVersion
+	json.Unmarshal(w.Body.Bytes(), &saved)
+	assert.Equal(t, 2, saved.Version)
+
+	w = doRequest(router, "GET", "/templates/note", "")
+	assert.Equal(t, 200, w.Code)
+	var got struct {
+		Version  int               `json:"version"`
+		Template string            `json:"template"`
+		Versions []TemplateVersion `json:"versions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, 2, got.Version)
+	assert.Contains(t, got.Template, "right")
+	assert.Len(t, got.Versions, 2)
+
+	w = doRequest(router, "GET", "/templates/note?version=1", "")
+	json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, 1, got.Version)
+	assert.Contains(t, got.Template, "v1")
+
+	w = doRequest(router, "POST", "/templates/note/print?version=1", `{"name": "Sam"}`)
+	assert.Equal(t, 200, w.Code)
+	w = doRequest(router, "POST", "/templates/note/print?version=3", `{}`)
+	assert.Equal(t, 404, w.Code)
+
+	w = doRequest(router, "GET", "/templates", "")
+	assert.Contains(t, w.Body.String(), `"name":"note"`)
+
+	w = doRequest(router, "DELETE", "/templates/note", "")
+	assert.Equal(t, 200, w.Code)
+	w = doRequest(router, "GET", "/templates/note", "")
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestTemplates_ValidatedOnSave(t *testing.T) {
+	templateStore = NewTemplateStore(t.TempDir())
+	router := setupTestRouter()
+
+	w := doRequest(router, "PUT", "/templates/typo", `{"receipt": [{"type": "line", "content": {{json .name}}, "alignment": "centre"}]}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid alignment")
+
+	w = doRequest(router, "GET", "/templates/typo", "")
+	assert.Equal(t, 404, w.Code, "invalid templates aren't stored")
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/codaea/simpleprint/imaging"
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the version of the server, set when it is built with
+// -ldflags "-X github.com/codaea/simpleprint/server.Version=v1.2.3"
+var Version = "dev"
+
+// barcodeSamples is a valid code of every barcode type, for the self-test
+var barcodeSamples = map[receipt.BarcodeType]string{
+	receipt.BarcodeUPCA:                "01234567890",
+	receipt.BarcodeUPCE:                "0123456",
+	receipt.BarcodeEAN13:               "590123412345",
+	receipt.BarcodeEAN8:                "9638507",
+	receipt.BarcodeCODE39:              "SIMPLE-39",
+	receipt.BarcodeITF:                 "12345678",
+	receipt.BarcodeCODABAR:             "A123456B",
+	receipt.BarcodeCODE93:              "Simple-93",
+	receipt.BarcodeCODE128:             "Simple-128",
+	receipt.BarcodeGS1128:              "(01)09501101530008",
+	receipt.BarcodeGS1DataBar:          "0950110153000",
+	receipt.BarcodeGS1DataBarTruncated: "0950110153000",
+	receipt.BarcodeGS1DataBarLimited:   "0950110153000",
+	receipt.BarcodeGS1DataBarExpanded:  "(01)09501101530008",
+}
+
+// selfTestModuleWidth is the widest module width up to 4 that fits b on the paper,
+// 2 for GS1 DataBar Expanded whose width isn't known
+func selfTestModuleWidth(b receipt.Barcode) int {
+	modules, ok := b.Modules()
+	if !ok {
+		return 2
+	}
+	return max(1, min(4, receipt.PaperWidth/modules))
+}
+
+// selfTestQRSizes are the module sizes the self-test prints QR codes in
+var selfTestQRSizes = []int{1, 2, 3, 4, 6, 8}
+
+var fonts = []receipt.FontType{receipt.FontA, receipt.FontB, receipt.FontC}
+
+// ruler is n characters numbering every column, the last digit of each column number
+func ruler(n int) string {
+	return strings.Repeat("1234567890", n/10+1)[:n]
+}
+
+// decodeBuilt decodes the request b builds, which fills in the defaults of the fields the builder leaves empty
+func decodeBuilt(b *receipt.Builder) (receipt.Request, error) {
+	body, err := json.Marshal(b.Build())
+	if err != nil {
+		return receipt.Request{}, err
+	}
+	var req receipt.Request
+	err = json.Unmarshal(body, &req)
+	return req, err
+}
+
+// TestPage is a short page to check a printer prints, with the paper width the server is set up for
+// and a ruler to check it against
+func TestPage(printer string) (receipt.Request, error) {
+	if printer == "" {
+		printer = "first USB printer"
+	}
+	cpl := receipt.CharsPerLine(receipt.FontA, 1)
+
+	b := receipt.New().
+		Line("simpleprint test page", receipt.Bold, receipt.Center, receipt.Size(2)).
+		Feed(1).
+		Line("Printer: "+printer).
+		Line(fmt.Sprintf("Paper width: %d dots, %d characters", receipt.PaperWidth, cpl)).
+		Line(ruler(cpl)).
+		Feed(1).
+		Line("Font A", receipt.Font(receipt.FontA)).
+		Line("Font B", receipt.Font(receipt.FontB)).
+		Line("Bold", receipt.Bold).
+		Line("Underline", receipt.Underline).
+		Line("Left", receipt.Left).
+		Line("Center", receipt.Center).
+		Line("Right", receipt.Right).
+		Feed(1).
+		Barcode("SIMPLEPRINT", receipt.BarcodeCODE128, receipt.Center).
+		Feed(1).
+		QR("https://github.com/codaea/simpleprint", receipt.Center).
+		FinalCut(receipt.CutFull)
+	return decodeBuilt(b)
+}
+
+// SelfTest is a diagnostic page to check a new printer in one go: the version and configuration,
+// every font at every size, rulers of the characters per line, the glyphs of each code page,
+// every barcode type, QR codes in several sizes and a gray ramp in each dither mode
+func SelfTest(printer string) (receipt.Request, error) {
+	if printer == "" {
+		printer = "first USB printer"
+	}
+	section := func(b *receipt.Builder, title string) {
+		b.Feed(1).Line(title, receipt.Bold, receipt.Underline)
+	}
+
+	b := receipt.New().
+		Line("SELF-TEST", receipt.Bold, receipt.Center, receipt.Size(2)).
+		Line("simpleprint "+Version, receipt.Center)
+
+	name, p := ActiveProfile()
+	width := fmt.Sprintf("%d dots", receipt.PaperWidth)
+	if p.Paper.WidthMM > 0 {
+		width += fmt.Sprintf(", %gmm at %d dpi", p.Paper.WidthMM, p.Paper.DPI)
+	}
+	section(b, "Configuration")
+	for _, setting := range [][2]string{
+		{"Profile", name},
+		{"Printer", printer},
+		{"Paper width", width},
+		{"Cut feed lines", strconv.Itoa(render.CutFeedLines)},
+		{"Features", fmt.Sprintf("cutter %t, drawer %t, QR %t", p.Features.Cutter, p.Features.Drawer, p.Features.QR)},
+		{"Code pages", fmt.Sprint(p.Features.CodePages)},
+		{"Default code page", string(p.DefaultCodePage)},
+		{"Default style", fmt.Sprintf("font %s, size %d, %s", p.DefaultStyle.Font, p.DefaultStyle.FontSize, p.DefaultStyle.Alignment)},
+		{"Assets", assetStore.dir},
+		{"Templates", templateStore.dir},
+		{"NV graphics", nvGraphics.store.dir},
+		{"NV legacy", strconv.FormatBool(nvGraphics.legacy)},
+	} {
+		b.Line(setting[0]+": "+setting[1], receipt.Font(receipt.FontB))
+	}
+
+	// each font on a line of its own, with every size printed as its number
+	section(b, "Fonts")
+	for _, f := range fonts {
+		b.Text(string(f)+" ", receipt.Font(f))
+		for size := 1; size <= receipt.MaxFontSize; size++ {
+			b.Text(strconv.Itoa(size), receipt.Font(f), receipt.Size(size))
+		}
+		b.Line("", receipt.Font(f))
+	}
+
+	section(b, "Characters per line")
+	for _, f := range fonts {
+		cpl := receipt.CharsPerLine(f, 1)
+		b.Line(fmt.Sprintf("Font %s: %d", f, cpl), receipt.Font(f))
+		b.Line(ruler(cpl), receipt.Font(f))
+	}
+
+	// only the code pages the printer has, all of them if the profile doesn't say
+	section(b, "Code pages")
+	codePages := p.Features.CodePages
+	if len(codePages) == 0 {
+		codePages = receipt.CodePages
+	}
+	for _, c := range codePages {
+		glyphs := c.Glyphs()
+		b.Line(string(c))
+		b.Line("   0 1 2 3 4 5 6 7 8 9 A B C D E F")
+		for row := 0; row < len(glyphs)/16; row++ {
+			cells := make([]string, 16)
+			for i, g := range glyphs[row*16 : row*16+16] {
+				cells[i] = string(g)
+			}
+			b.Line(fmt.Sprintf("%X_ %s", 8+row, strings.Join(cells, " ")), receipt.Encoding(c))
+		}
+	}
+
+	section(b, "Barcodes")
+	for _, t := range receipt.BarcodeTypes {
+		b.Line(string(t), receipt.Font(receipt.FontB))
+		code := receipt.Barcode{Code: barcodeSamples[t], BarcodeType: t, Alignment: receipt.AlignCenter}
+		code.ModuleWidth = selfTestModuleWidth(code)
+		b.Add(code)
+	}
+
+	section(b, "QR codes")
+	for _, size := range selfTestQRSizes {
+		b.Line(fmt.Sprintf("Size %d", size), receipt.Font(receipt.FontB))
+		b.QR("https://github.com/codaea/simpleprint", receipt.Center, receipt.Size(size))
+	}
+
+	section(b, "Dithering")
+	ramp, err := imaging.EncodeDataURI(grayRamp(receipt.PaperWidth, 48))
+	if err != nil {
+		return receipt.Request{}, err
+	}
+	for _, mode := range []receipt.DitherMode{receipt.DitherNone, receipt.DitherFloydSteinberg} {
+		b.Line(string(mode), receipt.Font(receipt.FontB))
+		b.Add(receipt.Image{Data: ramp, DitherMode: mode})
+	}
+
+	b.FinalCut(receipt.CutFull)
+	return decodeBuilt(b)
+}
+
+// grayRamp is a gradient from white on the left to black on the right
+func grayRamp(width, height int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		gray := color.Gray{Y: uint8(255 - 255*x/(width-1))}
+		for y := 0; y < height; y++ {
+			img.SetGray(x, y, gray)
+		}
+	}
+	return img
+}
+
+// handlePrintSelfTest prints the self-test page
+func handlePrintSelfTest(c *gin.Context) {
+	req, err := SelfTest(profile.URI)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !tryLockPrinter(c) {
+		return
+	}
+	defer printerMutex.Unlock()
+
+	p := c.MustGet("printer").(*render.ESCPOS)
+	if err := Print(p, req); err != nil {
+		c.JSON(printErrorStatus(err), errorResponse(err))
+		return
+	}
+	c.JSON(200, gin.H{"success": true})
+}
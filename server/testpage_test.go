@@ -0,0 +1,46 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfTest(t *testing.T) {
+	req, err := SelfTest("/dev/usb/lp0")
+	assert.NoError(t, err)
+
+	barcodes := map[receipt.BarcodeType]bool{}
+	codePages := map[receipt.CodePage]bool{}
+	var qrs, images int
+	for _, item := range req.Receipt {
+		switch v := item.(type) {
+		case receipt.Barcode:
+			barcodes[v.BarcodeType] = true
+			if modules, ok := v.Modules(); ok {
+				assert.LessOrEqual(t, modules*v.ModuleWidth, receipt.PaperWidth, v.BarcodeType)
+			}
+		case receipt.Line:
+			if v.CodePage != "" {
+				codePages[v.CodePage] = true
+			}
+		case receipt.QRCode:
+			qrs++
+		case receipt.Image:
+			images++
+		}
+	}
+	assert.Len(t, barcodes, len(receipt.BarcodeTypes))
+	assert.Len(t, codePages, len(receipt.CodePages))
+	assert.Equal(t, len(selfTestQRSizes), qrs)
+	assert.Equal(t, 2, images)
+
+	errs, _ := checkLayout(req)
+	assert.Empty(t, errs, "everything fits the paper")
+}
+
+func TestHandlePrintSelfTest(t *testing.T) {
+	w := doRequest(setupTestRouter(), "POST", "/print/self-test", "")
+	assert.Equal(t, 200, w.Code)
+}
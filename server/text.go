@@ -0,0 +1,147 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/gin-gonic/gin"
+)
+
+// TextOptions are the query parameters of a plain text print
+type TextOptions struct {
+	Font      receipt.FontType
+	FontSize  int
+	Alignment receipt.AlignmentType
+	// Markup interprets **bold**, __underline__ and --- lines
+	Markup bool
+}
+
+// parseTextOptions reads the query parameters, the ones left out are the profile's default style
+func parseTextOptions(c *gin.Context) (TextOptions, error) {
+	style := receipt.TextStyle{}.WithDefaults()
+	opts := TextOptions{Font: style.Font, FontSize: style.FontSize, Alignment: style.Alignment}
+
+	switch font := c.Query("font"); font {
+	case "":
+	case "A", "B", "C":
+		opts.Font = receipt.FontType(font)
+	default:
+		return opts, fmt.Errorf("invalid font: %s. Must be A, B, or C", font)
+	}
+	if size := c.Query("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 1 || n > 8 {
+			return opts, fmt.Errorf("invalid size: %s. Must be 1-8", size)
+		}
+		opts.FontSize = n
+	}
+	if alignment := c.Query("alignment"); alignment != "" {
+		a, err := receipt.ParseAlignment(alignment)
+		if err != nil {
+			return opts, err
+		}
+		opts.Alignment = a
+	}
+	if markup := c.Query("markup"); markup != "" {
+		b, err := strconv.ParseBool(markup)
+		if err != nil {
+			return opts, fmt.Errorf("invalid markup: %s. Must be true or false", markup)
+		}
+		opts.Markup = b
+	}
+	return opts, nil
+}
+
+// parseTextMarkup splits **bold** and __underline__ into runs, markers that aren't closed are printed as they are
+func parseTextMarkup(s string) []textRun {
+	var runs []textRun
+	var cur strings.Builder
+	bold, underline := false, false
+	flush := func() {
+		runs = appendRun(runs, textRun{cur.String(), bold, underline})
+		cur.Reset()
+	}
+
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		if strings.HasPrefix(rest, "**") && (bold || strings.Contains(rest[2:], "**")) {
+			flush()
+			bold = !bold
+			i += 2
+			continue
+		}
+		if strings.HasPrefix(rest, "__") && (underline || strings.Contains(rest[2:], "__")) {
+			flush()
+			underline = !underline
+			i += 2
+			continue
+		}
+		cur.WriteByte(s[i])
+		i++
+	}
+	flush()
+	return runs
+}
+
+// textToReceipt turns plain text into a print request. Lines that fit the paper are printed
+// as they are, so spacing is kept, longer lines are word wrapped.
+func textToReceipt(text string, opts TextOptions) receipt.Request {
+	width := receipt.CharsPerLine(opts.Font, opts.FontSize)
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var items []receipt.Item
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.ReplaceAll(strings.TrimRight(line, " \t"), "\t", "    ")
+		if opts.Markup && line == "---" {
+			items = append(items, separator())
+			continue
+		}
+
+		runs := []textRun{{text: line}}
+		if opts.Markup {
+			runs = parseTextMarkup(line)
+		}
+		lines := [][]textRun{runs}
+		if runsLen(runs) > width {
+			indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+			if len(indent) >= width/2 {
+				indent = ""
+			}
+			lines = wrapRuns(runs, width, indent, indent)
+		}
+		items = append(items, runsToItems(lines, receipt.TextStyle{Font: opts.Font, FontSize: opts.FontSize, Alignment: opts.Alignment})...)
+	}
+
+	return receipt.Request{Receipt: items, Cut: receipt.CutFull, Copies: 1}
+}
+
+// handlePrintText prints the plain text body, formatted by the query parameters
+func handlePrintText(c *gin.Context) {
+	opts, err := parseTextOptions(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	req := textToReceipt(string(body), opts)
+
+	if !tryLockPrinter(c) {
+		return
+	}
+	defer printerMutex.Unlock()
+
+	p := c.MustGet("printer").(*render.ESCPOS)
+	if err := Print(p, req); err != nil {
+		c.JSON(printErrorStatus(err), errorResponse(err))
+		return
+	}
+	c.JSON(200, gin.H{"success": true})
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextToReceipt(t *testing.T) {
+	req := textToReceipt("Disk   usage\n\n"+strings.Repeat("word ", 12)+"\n", TextOptions{Font: receipt.FontA, FontSize: 2, Alignment: receipt.AlignLeft})
+
+	assert.Equal(t, "Disk   usage", req.Receipt[0].(receipt.Line).Content, "lines that fit keep their spacing")
+	assert.Equal(t, "", req.Receipt[1].(receipt.Line).Content)
+	assert.Equal(t, "word word word word word", req.Receipt[2].(receipt.Line).Content, "24 characters fit at size 2")
+	assert.Equal(t, 2, req.Receipt[2].(receipt.Line).FontSize)
+	assert.Len(t, req.Receipt, 5)
+}
+
+func TestTextToReceipt_Markup(t *testing.T) {
+	text := "**Total** __due__ **now\n---\n__init__.py"
+
+	plain := textToReceipt(text, TextOptions{Font: receipt.FontA, FontSize: 1, Alignment: receipt.AlignLeft})
+	assert.Equal(t, "---", plain.Receipt[1].(receipt.Line).Content, "markup is off by default")
+
+	req := textToReceipt(text, TextOptions{Font: receipt.FontA, FontSize: 1, Alignment: receipt.AlignLeft, Markup: true})
+	assert.Equal(t, receipt.Text{Type: "text", Content: "Total", FontSize: 1, Font: receipt.FontA, Alignment: receipt.AlignLeft, Bold: true}, req.Receipt[0])
+	assert.Equal(t, " ", req.Receipt[1].(receipt.Text).Content)
+	assert.True(t, req.Receipt[2].(receipt.Text).Underline)
+	assert.Equal(t, " **now\n", req.Receipt[3].(receipt.Text).Content, "markers that aren't closed are printed")
+	assert.Equal(t, separator(), req.Receipt[4])
+	assert.Equal(t, "init", req.Receipt[5].(receipt.Text).Content)
+	assert.True(t, req.Receipt[5].(receipt.Text).Underline)
+	assert.Equal(t, ".py\n", req.Receipt[6].(receipt.Text).Content)
+}
+
+func TestParseTextOptions_ProfileDefaults(t *testing.T) {
+	restoreSettings(t)
+	receipt.DefaultStyle = receipt.TextStyle{Font: receipt.FontB, Alignment: receipt.AlignCenter}
+	receipt.DefaultCodePage = receipt.CodePagePC850
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/print/text?size=2", nil)
+	opts, err := parseTextOptions(c)
+	assert.NoError(t, err)
+	assert.Equal(t, TextOptions{Font: receipt.FontB, FontSize: 2, Alignment: receipt.AlignCenter}, opts)
+
+	req := textToReceipt("Café", opts)
+	assert.Equal(t, receipt.Line{Type: "line", Content: "Café", FontSize: 2, Font: receipt.FontB, Alignment: receipt.AlignCenter, CodePage: receipt.CodePagePC850}, req.Receipt[0])
+}
+
+func TestPrintText(t *testing.T) {
+	router := setupTestRouter()
+
+	w := doRequest(router, "POST", "/print/text?font=B&size=2&alignment=center&markup=true", "**Backup done**")
+	assert.Equal(t, 200, w.Code)
+
+	w = doRequest(router, "POST", "/print/text?size=9", "hello")
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid size")
+}
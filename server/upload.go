@@ -0,0 +1,108 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/codaea/simpleprint/render"
+	"github.com/gin-gonic/gin"
+)
+
+// maxImageUpload is the largest image upload in bytes, the body is cut off after it
+var maxImageUpload int64 = 32 << 20
+
+// readImageUpload reads a multipart/form-data image upload part by part,
+// so the file is decoded straight from the request body instead of being buffered first
+func readImageUpload(c *gin.Context) (receipt.Image, error) {
+	img := receipt.Image{Type: "image"}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageUpload)
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return img, err
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return img, err
+		}
+
+		if part.FileName() != "" {
+			if part.FormName() != "file" {
+				return img, fmt.Errorf("unknown file field %s. The image must be sent as file", part.FormName())
+			}
+			if img.Img != nil {
+				return img, fmt.Errorf("only one image file can be uploaded")
+			}
+			img.Img, _, err = image.Decode(part)
+			if err != nil {
+				return img, fmt.Errorf("error decoding image %s: %w", part.FileName(), err)
+			}
+			continue
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, 1024))
+		if err != nil {
+			return img, err
+		}
+		switch part.FormName() {
+		case "alignment":
+			img.Alignment, err = receipt.ParseAlignment(string(value))
+		case "width":
+			img.Width, err = strconv.Atoi(string(value))
+		case "dither_mode":
+			img.DitherMode = receipt.DitherMode(strings.ToLower(string(value)))
+			if img.DitherMode != receipt.DitherNone && img.DitherMode != receipt.DitherFloydSteinberg {
+				err = errors.New("must be none or floydsteinberg")
+			}
+		default:
+			return img, fmt.Errorf("unknown field %s. Only alignment, width and dither_mode can be set", part.FormName())
+		}
+		if err != nil {
+			return img, fmt.Errorf("invalid %s: %v", part.FormName(), err)
+		}
+	}
+
+	if img.Img == nil {
+		return img, fmt.Errorf("no image file in upload")
+	}
+	return img, nil
+}
+
+// handlePrintImage prints a single image uploaded as multipart/form-data,
+// which avoids base64 encoding big photos into JSON
+func handlePrintImage(c *gin.Context) {
+	// Lock before reading the upload so concurrent uploads don't all end up in memory
+	if !tryLockPrinter(c) {
+		return
+	}
+	defer printerMutex.Unlock()
+
+	p := c.MustGet("printer").(*render.ESCPOS)
+
+	img, err := readImageUpload(c)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(413, gin.H{"error": fmt.Sprintf("upload is larger than %d bytes", tooLarge.Limit)})
+		return
+	} else if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := Print(p, receipt.Request{Receipt: []receipt.Item{img}, Cut: receipt.CutFull}); err != nil {
+		c.JSON(printErrorStatus(err), errorResponse(err))
+		return
+	}
+	c.JSON(200, gin.H{"success": true})
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// multipartImageRequest uploads a photo as fileField, or no file if it is empty
+func multipartImageRequest(fileField string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	if fileField != "" {
+		fw, _ := mw.CreateFormFile(fileField, "photo.jpg")
+		jpeg.Encode(fw, image.NewGray(image.Rect(0, 0, 1200, 900)), nil)
+	}
+	mw.Close()
+
+	req, _ := http.NewRequest("POST", "/print/image", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestHandlePrintImage(t *testing.T) {
+	router := setupTestRouter()
+
+	req := multipartImageRequest("file", map[string]string{
+		"alignment":   "center",
+		"width":       "384",
+		"dither_mode": "FloydSteinberg",
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestHandlePrintImage_Invalid(t *testing.T) {
+	router := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, multipartImageRequest("", map[string]string{"alignment": "center"}))
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "no image file")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, multipartImageRequest("file", map[string]string{"alignment": "middle"}))
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid alignment")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, multipartImageRequest("file", map[string]string{"width": "wide"}))
+	assert.Equal(t, 400, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, multipartImageRequest("image", nil))
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "must be sent as file")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, multipartImageRequest("file", map[string]string{"dither_mode": "atkinson"}))
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid dither_mode")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, multipartImageRequest("file", map[string]string{"dither-mode": "none"}))
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "unknown field dither-mode")
+}
+
+func TestHandlePrintImage_TooLarge(t *testing.T) {
+	router := setupTestRouter()
+	defer func(limit int64) { maxImageUpload = limit }(maxImageUpload)
+	maxImageUpload = 1024
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, multipartImageRequest("file", nil))
+	assert.Equal(t, 413, w.Code)
+}
+
+func TestHandlePrintImage_PrinterBusy(t *testing.T) {
+	router := setupTestRouter()
+
+	printerMutex.Lock()
+	defer printerMutex.Unlock()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, multipartImageRequest("file", nil))
+	assert.Equal(t, 503, w.Code)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/codaea/simpleprint/receipt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlePrint_ValidationErrors(t *testing.T) {
+	router := setupTestRouter()
+
+	w := doRequest(router, "POST", "/print", `{"receipt": [
+		{"type": "line", "content": "a", "font_size": 12},
+		{"type": "qr", "code": "b", "size": 20, "colour": "red"}
+	]}`)
+	assert.Equal(t, 400, w.Code)
+
+	var resp struct {
+		Errors []receipt.FieldError `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []receipt.FieldError{
+		{Path: "/receipt/0/font_size", Message: "invalid font_size: 12. Must be 1-8"},
+		{Path: "/receipt/1/colour", Message: "unknown field"},
+	}, resp.Errors)
+}
+
+func TestDecodeYAMLRequest_ValidationLines(t *testing.T) {
+	_, err := decodeYAMLRequest([]byte("receipt:\n  - type: line\n    content: ok\n  - type: feed\n    lines: 300\n"))
+	var verr *receipt.ValidationError
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, []receipt.FieldError{{Path: "/receipt/1/lines", Message: "invalid lines: 300. Must be 0-255", Line: 4}}, verr.Errors)
+}